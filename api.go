@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/avegner/exercism-bench/exercism"
+)
+
+// defaultAPIURL is a base URL of exercism API.
+const defaultAPIURL = "https://exercism.io/api"
+
+// apiSource gets solutions from JSON endpoints of exercism API with a token.
+type apiSource struct {
+	cfg *config
+	tq  chan<- task
+	url string // base URL of API
+}
+
+type apiSolutionsPage struct {
+	Results []struct {
+		UUID string `json:"uuid"`
+	} `json:"results"`
+	Meta struct {
+		TotalPages uint64 `json:"total_pages"`
+	} `json:"meta"`
+}
+
+type apiSolution struct {
+	Solution struct {
+		User struct {
+			Handle string `json:"handle"`
+		} `json:"user"`
+		FileDownloadBaseURL string   `json:"file_download_base_url"`
+		Files               []string `json:"files"`
+	} `json:"solution"`
+}
+
+var errNoAPISolutionCode = errors.New("can't find solution code file")
+
+// ListUUIDs returns sorted UUIDs of solutions of all pages of community solutions.
+func (s *apiSource) ListUUIDs(ctx context.Context) ([]string, error) {
+	return listUUIDs(ctx, s.cfg, s.tq, s.groupUUIDs)
+}
+
+func (s *apiSource) groupUUIDs(ctx context.Context, page uint64) (uuids []string, total uint64, urlv string, err error) {
+	baseURL := strings.Join([]string{s.url, "v2", "tracks", trackLang, "exercises", s.cfg.exercise, "community_solutions"}, "/")
+	content, urlv, err := getPage(ctx, s.cfg, pageTimeoutFlag, baseURL, map[string]string{
+		"page": strconv.FormatUint(page, 10),
+	})
+	if err != nil {
+		return nil, 0, urlv, err
+	}
+	sp := &apiSolutionsPage{}
+	if err = json.Unmarshal([]byte(content), sp); err != nil {
+		return nil, 0, urlv, fmt.Errorf("invalid response: %v", err)
+	}
+	for _, r := range sp.Results {
+		uuids = append(uuids, r.UUID)
+	}
+	return uuids, sp.Meta.TotalPages, urlv, nil
+}
+
+// FetchSolution returns code of a solution and its author name.
+func (s *apiSource) FetchSolution(ctx context.Context, uuid string) (code, author string, err error) {
+	code, author, urlv, err := s.solutionCode(ctx, uuid)
+	if err != nil {
+		return "", "", fmt.Errorf("download of %s failed: %v", urlv, err)
+	}
+	return code, author, nil
+}
+
+func (s *apiSource) solutionCode(ctx context.Context, uuid string) (code, author, urlv string, err error) {
+	sol, urlv, err := s.solution(ctx, uuid)
+	if err != nil {
+		return "", "", urlv, err
+	}
+	// a solution file is the only non-test Go file
+	for _, fn := range sol.Solution.Files {
+		if path.Ext(fn) != ".go" || strings.HasSuffix(fn, "_test.go") {
+			continue
+		}
+		if code, urlv, err = s.file(ctx, sol, fn); err != nil {
+			return "", "", urlv, err
+		}
+		return normalizeLineEndings(code), sol.Solution.User.Handle, urlv, nil
+	}
+	return "", "", urlv, errNoAPISolutionCode
+}
+
+// FetchTestSuite gets test suite of the first solution of the first page of community solutions.
+func (s *apiSource) FetchTestSuite(ctx context.Context) (suite map[string]string, err error) {
+	uuid, err := firstUUID(ctx, s.cfg, s.tq, s.groupUUIDs)
+	if err != nil {
+		return nil, err
+	}
+	suite, urlv, err := s.testSuite(ctx, uuid)
+	if err != nil {
+		return nil, fmt.Errorf("download of test suite %s failed: %v", urlv, err)
+	}
+	return suite, nil
+}
+
+func (s *apiSource) testSuite(ctx context.Context, uuid string) (suite map[string]string, urlv string, err error) {
+	sol, urlv, err := s.solution(ctx, uuid)
+	if err != nil {
+		return nil, urlv, err
+	}
+	suite = make(map[string]string)
+	for _, fn := range sol.Solution.Files {
+		if !strings.HasSuffix(fn, "_test.go") {
+			continue
+		}
+		// names are used as file names, so nested ones are rejected like in pages
+		if fn != path.Base(fn) || strings.Contains(fn, `\`) {
+			return nil, urlv, fmt.Errorf("invalid test file name %q", fn)
+		}
+		code, furl, err := s.file(ctx, sol, fn)
+		if err != nil {
+			return nil, furl, err
+		}
+		suite[fn] = normalizeLineEndings(code)
+	}
+	if len(suite) == 0 {
+		return nil, urlv, exercism.ErrNoTestSuite
+	}
+	return suite, urlv, nil
+}
+
+// solution gets solution metadata with names of its files.
+func (s *apiSource) solution(ctx context.Context, uuid string) (sol *apiSolution, urlv string, err error) {
+	content, urlv, err := getPage(ctx, s.cfg, solTimeoutFlag, strings.Join([]string{s.url, "v1", "solutions", uuid}, "/"), nil)
+	if err != nil {
+		return nil, urlv, err
+	}
+	sol = &apiSolution{}
+	if err = json.Unmarshal([]byte(content), sol); err != nil {
+		return nil, urlv, fmt.Errorf("invalid response: %v", err)
+	}
+	return sol, urlv, nil
+}
+
+// file gets content of a solution file.
+func (s *apiSource) file(ctx context.Context, sol *apiSolution, name string) (content, urlv string, err error) {
+	return getPage(ctx, s.cfg, solTimeoutFlag, strings.TrimSuffix(sol.Solution.FileDownloadBaseURL, "/")+"/"+name, nil)
+}
+
+// tokenTransport adds a bearer token to requests to API.
+// Other requests are sent w/o it to not leak the token.
+type tokenTransport struct {
+	base   http.RoundTripper
+	prefix string // URL prefix of API
+	token  string
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasPrefix(req.URL.String(), t.prefix) {
+		return t.base.RoundTrip(req)
+	}
+	// requests must not be modified by round trippers
+	r := req.WithContext(req.Context())
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(r)
+}
+
+// configureAPI sets up a client to send a token to API if API mode is requested by flags.
+func configureAPI(cfg *config) error {
+	if !apiFlag {
+		return nil
+	}
+	if apiTokenFlag == "" {
+		return errors.New("API mode requires token; copy it from exercism CLI config or settings page to -api-token flag")
+	}
+	base := cfg.client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	cfg.client.Transport = &tokenTransport{
+		base:   base,
+		prefix: apiURL() + "/",
+		token:  apiTokenFlag,
+	}
+	return nil
+}
+
+// apiURL returns a base URL of API set by flags.
+func apiURL() string {
+	return strings.TrimSuffix(apiURLFlag, "/")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log"
+	"sort"
+)
+
+// authorStats is a contribution of an author to an exercise.
+type authorStats struct {
+	name      string
+	solutions int
+	top       int // top places across benchmarks
+}
+
+// countAuthors counts solution files of each author.
+// Files w/o an author in their names are ignored.
+func countAuthors(fnames []string) map[string]*authorStats {
+	authors := make(map[string]*authorStats)
+	for _, fn := range fnames {
+		_, _, author := parseSolutionFileName(fn)
+		if author == "" {
+			continue
+		}
+		au := authors[author]
+		if au == nil {
+			au = &authorStats{name: author}
+			authors[author] = au
+		}
+		au.solutions++
+	}
+	return authors
+}
+
+// countTopPlaces counts places not greater than top of each author solutions for each benchmark.
+// Results of solutions absent in authors are ignored.
+func countTopPlaces(authors map[string]*authorStats, sstats []*solutionStats, bnames []string, top int) {
+	for _, bn := range bnames {
+		sortSolutionStatsByBench(sstats, bn, sortKeys())
+		for i, st := range sstats {
+			if i >= top || st.bstats[bn] == nil {
+				break
+			}
+			_, _, author := parseSolutionFileName(st.file)
+			if au := authors[author]; au != nil {
+				au.top++
+			}
+		}
+	}
+}
+
+// printTopAuthors prints authors sorted by top places if results are used and number of solutions.
+func printTopAuthors(lg *log.Logger, authors map[string]*authorStats, withTop bool) {
+	sorted := make([]*authorStats, 0, len(authors))
+	for _, au := range authors {
+		sorted = append(sorted, au)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		l, r := sorted[i], sorted[j]
+		return l.top > r.top ||
+			(l.top == r.top && l.solutions > r.solutions) ||
+			(l.top == r.top && l.solutions == r.solutions && l.name < r.name)
+	})
+
+	if withTop {
+		lg.Printf("sorted by top %d places across benchmarks, solutions", topFlag)
+	} else {
+		lg.Printf("sorted by solutions")
+	}
+	lg.Println()
+	for i, au := range sorted {
+		if withTop {
+			lg.Printf("[%5d] %-40s: %5d solutions %5d top places", i+1, au.name, au.solutions, au.top)
+		} else {
+			lg.Printf("[%5d] %-40s: %5d solutions", i+1, au.name, au.solutions)
+		}
+	}
+}
@@ -1,36 +1,45 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
 	"io/ioutil"
+	"math"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strconv"
-)
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
-var (
-	benchNameRE       = regexp.MustCompile("Benchmark([[:alnum:]]|_)+")
-	benchTimeRE       = regexp.MustCompile("([[:digit:]]+(.[[:digit:]]+)?) ns/op")
-	benchThroughputRE = regexp.MustCompile("([[:digit:]]+(.[[:digit:]]+)?) MB/s")
-	benchMemRE        = regexp.MustCompile(`([[:digit:]]+) B/op\s+([[:digit:]]+) allocs/op`)
-	benchStatsRE      = regexp.MustCompile(
-		fmt.Sprintf("%s(-[[:digit:]]+)?\\s+[[:digit:]]+\\s+%s\\s+(%s\\s+)?(%s)?",
-			benchNameRE, benchTimeRE, benchThroughputRE, benchMemRE))
+	"github.com/avegner/exercism-bench/exercism"
 )
 
+var errNoBenchmarks = exercism.ErrNoBenchmarks
+
 type benchStats struct {
 	time       float64 // ns
 	throughput float64 // MB
 	mem        int64   // B
 	allocs     int64
+	samples    []float64 // time of each run
+	tags       []string  // marks added by post-processing
 }
 
 func (st *benchStats) String() string {
-	s := fmt.Sprintf("%15.1f ns", st.time)
+	t, u := st.time, "ns"
+	if unitsFlag == "auto" {
+		t, u = scaleTime(st.time)
+	}
+	s := fmt.Sprintf("%15.*f %-2s", precisionFlag, t, u)
 	if st.throughput != -1 {
-		s += fmt.Sprintf(" %18.1f MB/s", st.throughput)
+		s += fmt.Sprintf(" %18.*f MB/s", precisionFlag, st.throughput)
 	}
 	if st.mem != -1 && st.allocs != -1 {
 		s += fmt.Sprintf(" %15d B mem %15d allocs", st.mem, st.allocs)
@@ -38,118 +47,558 @@ func (st *benchStats) String() string {
 	return s
 }
 
+// allocRate returns bytes allocated per ns of time (mem / time) or -1 if mem stats are absent.
+func (st *benchStats) allocRate() float64 {
+	if st.mem == -1 || st.time <= 0 {
+		return -1
+	}
+	return float64(st.mem) / st.time
+}
+
+// samplesString returns time samples of all runs in ns.
+func (st *benchStats) samplesString() string {
+	ss := make([]string, len(st.samples))
+	for i, t := range st.samples {
+		ss[i] = strconv.FormatFloat(t, 'f', precisionFlag, 64)
+	}
+	return strings.Join(ss, " ")
+}
+
+// scaleTime converts ns time to the most readable unit.
+func scaleTime(ns float64) (t float64, unit string) {
+	switch {
+	case ns >= 1e9:
+		return ns / 1e9, "s"
+	case ns >= 1e6:
+		return ns / 1e6, "ms"
+	case ns >= 1e3:
+		return ns / 1e3, "µs"
+	default:
+		return ns, "ns"
+	}
+}
+
+// timeCV returns coefficient of variation of time samples.
+func (st *benchStats) timeCV() float64 {
+	if len(st.samples) < 2 {
+		return 0
+	}
+	mean := 0.0
+	for _, t := range st.samples {
+		mean += t
+	}
+	mean /= float64(len(st.samples))
+	if mean == 0 {
+		return 0
+	}
+	v := 0.0
+	for _, t := range st.samples {
+		v += (t - mean) * (t - mean)
+	}
+	v /= float64(len(st.samples) - 1)
+	return math.Sqrt(v) / mean
+}
+
+func (st *benchStats) tag(t string) {
+	st.tags = append(st.tags, t)
+}
+
+func (st *benchStats) tagsString() string {
+	if len(st.tags) == 0 {
+		return ""
+	}
+	return " " + colorize(strings.Join(st.tags, " "), colorYellow)
+}
+
+// ratioString returns ratios of stats to baseline ones or an empty string w/o baseline.
+// Ratios of absent or zero baseline stats are omitted.
+func (st *benchStats) ratioString(base *benchStats) string {
+	if base == nil || base.time == 0 {
+		return ""
+	}
+	s := fmt.Sprintf(" %8.2fx time", st.time/base.time)
+	if st.mem != -1 && base.mem > 0 {
+		s += fmt.Sprintf(" %8.2fx mem", float64(st.mem)/float64(base.mem))
+	}
+	if st.allocs != -1 && base.allocs > 0 {
+		s += fmt.Sprintf(" %8.2fx allocs", float64(st.allocs)/float64(base.allocs))
+	}
+	return s
+}
+
 type solutionStats struct {
-	name   string
-	bstats map[string]*benchStats
-	size   uint // symbols except comments and white spaces
+	file      string
+	name      string
+	goVersion string
+	bstats    map[string]*benchStats
+	size      uint     // symbols except comments and white spaces or tokens except comments
+	partial   bool     // go test failed after some benchmarks
+	failed    []string // failed tests
+}
+
+// mergeRounds merges stats of the same solutions benched in several rounds keeping order of first appearance.
+// Runs of all rounds are reduced by the reducer set by flags, failed tests of all rounds are kept.
+func mergeRounds(sstats []*solutionStats) (merged []*solutionStats) {
+	byName := make(map[string][]*solutionStats)
+	for _, st := range sstats {
+		if _, ok := byName[st.name]; !ok {
+			merged = append(merged, st)
+		}
+		byName[st.name] = append(byName[st.name], st)
+	}
+
+	for _, st := range merged {
+		rounds := byName[st.name]
+		runs := make(map[string][]*exercism.BenchStats)
+		failed, seen := []string{}, make(map[string]bool)
+		for _, r := range rounds {
+			st.partial = st.partial || r.partial
+			// a test can fail in some rounds only
+			for _, tn := range r.failed {
+				if !seen[tn] {
+					seen[tn] = true
+					failed = append(failed, tn)
+				}
+			}
+			for bn, bst := range r.bstats {
+				runs[bn] = append(runs[bn], toLibBenchStats(bst))
+			}
+		}
+		st.bstats = make(map[string]*benchStats, len(runs))
+		for bn, rs := range runs {
+			// each round is a reduced run, time is reduced from samples of all runs
+			lbst := exercism.ReduceBenchStats(rs, reduceFlag)
+			lbst.Samples = nil
+			for _, r := range rs {
+				lbst.Samples = append(lbst.Samples, r.Samples...)
+			}
+			if reduceFlag != exercism.ReduceMin {
+				lbst.Time = exercism.ReduceValues(lbst.Samples, reduceFlag)
+			}
+			st.bstats[bn] = fromLibBenchStats(lbst)
+		}
+		if len(failed) != 0 {
+			st.failed = failed
+		}
+	}
+	return merged
 }
 
-// sort sorts by time (the most important), mem, allocs and size (the least).
-func sortSolutionStatsByBench(sstats []*solutionStats, benchName string) {
+// sortKeyNames are names of default sort keys in order of precedence.
+var sortKeyNames = []string{"time", "throughput", "mem", "allocs", "size"}
+
+// extraSortKeyNames are names of sort keys used only if they are set explicitly.
+var extraSortKeyNames = []string{"alloc-rate"}
+
+// sortSolutionStatsByBench sorts by given keys in order of precedence, e.g. time (the most important),
+// throughput, mem, allocs and size (the least). Throughput is sorted in descending order, the rest in ascending one.
+// Absent throughput and mem stats are equal to -1 for all solutions, so they don't affect the order.
+func sortSolutionStatsByBench(sstats []*solutionStats, benchName string, keys []string) {
 	sort.SliceStable(sstats, func(i, j int) bool {
 		lh, rh := sstats[i].bstats[benchName], sstats[j].bstats[benchName]
-		return lh.time < rh.time ||
-			(lh.time == rh.time &&
-				lh.throughput < rh.throughput) ||
-			(lh.time == rh.time &&
-				lh.throughput == rh.throughput &&
-				lh.mem < rh.mem) ||
-			(lh.time == rh.time &&
-				lh.throughput == rh.throughput &&
-				lh.mem == rh.mem &&
-				lh.allocs < rh.allocs) ||
-			(lh.time == rh.time &&
-				lh.throughput == rh.throughput &&
-				lh.mem == rh.mem &&
-				lh.allocs == rh.allocs &&
-				sstats[i].size < sstats[j].size)
+		// solutions w/o a given benchmark go last
+		if lh == nil || rh == nil {
+			return lh != nil && rh == nil
+		}
+		for _, k := range keys {
+			var l, r float64
+			switch k {
+			case "time":
+				l, r = lh.time, rh.time
+			case "throughput":
+				// higher throughput is better
+				l, r = -lh.throughput, -rh.throughput
+			case "mem":
+				l, r = float64(lh.mem), float64(rh.mem)
+			case "allocs":
+				l, r = float64(lh.allocs), float64(rh.allocs)
+			case "size":
+				l, r = float64(sstats[i].size), float64(sstats[j].size)
+			case "alloc-rate":
+				// solutions w/o the rate go last
+				l, r = lh.allocRate(), rh.allocRate()
+				if l == -1 {
+					l = math.Inf(1)
+				}
+				if r == -1 {
+					r = math.Inf(1)
+				}
+			}
+			if l != r {
+				return l < r
+			}
+		}
+		return false
 	})
 }
 
-// getBenchNames looks for benchmark names in test suite files.
-// All nested dirs in test suite dir are ignored.
-func getBenchNames(testSuitePath string) (names []string, err error) {
+// sortKeys returns sort keys set by flags in order of precedence.
+// Default keys are all ones w/ or w/o size depending on include size flag.
+func sortKeys() []string {
+	if sortKeysFlag != "" {
+		return strings.Split(sortKeysFlag, ",")
+	}
+	if sortBySizeFlag {
+		return sortKeyNames
+	}
+	return sortKeyNames[:len(sortKeyNames)-1]
+}
+
+// checkSortKeys checks comma-separated sort keys are known and not repeated.
+func checkSortKeys(keys string) error {
+	seen := make(map[string]bool)
+	for _, k := range strings.Split(keys, ",") {
+		known := false
+		names := append(append([]string(nil), sortKeyNames...), extraSortKeyNames...)
+		for _, n := range names {
+			known = known || k == n
+		}
+		if !known {
+			return fmt.Errorf("unknown sort key %q, known ones: %s", k, strings.Join(names, ", "))
+		}
+		if seen[k] {
+			return fmt.Errorf("sort key %q is repeated", k)
+		}
+		seen[k] = true
+	}
+	return nil
+}
+
+// checkTestSuite checks that test suite dir has test files and all Go files in it can be parsed.
+// Test files can't be compiled w/o a solution, so only syntax is validated.
+func checkTestSuite(testSuitePath string) error {
 	fis, err := ioutil.ReadDir(testSuitePath)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
+	fs := token.NewFileSet()
+	tests := 0
 	for _, fi := range fis {
-		if !regular(fi) {
+		if !regular(fi) || filepath.Ext(fi.Name()) != ".go" {
 			continue
 		}
-		// read each test file
-		bs, err := ioutil.ReadFile(filepath.Join(testSuitePath, fi.Name()))
+		if strings.HasSuffix(fi.Name(), "_test.go") {
+			tests++
+		}
+		fp := filepath.Join(testSuitePath, fi.Name())
+		if _, err := parser.ParseFile(fs, fp, nil, parser.AllErrors); err != nil {
+			return fmt.Errorf("test suite file %s is invalid: %v", fp, err)
+		}
+	}
+	if tests == 0 {
+		return fmt.Errorf("no _test.go files found in test suite %s", testSuitePath)
+	}
+	return nil
+}
+
+// typeCheckTestSuite checks that a package of test suite compiles on its own.
+// Names declared by a solution are undefined w/o it, so only such errors are ignored.
+// Files of an external test package import a solution package, so they aren't checked.
+func typeCheckTestSuite(testSuitePath string) error {
+	fis, err := ioutil.ReadDir(testSuitePath)
+	if err != nil {
+		return err
+	}
+
+	// parse files go test would build
+	fs := token.NewFileSet()
+	bctx := build.Default
+	bctx.BuildTags = buildTags()
+	files := []*ast.File{}
+	for _, fi := range fis {
+		if !regular(fi) || filepath.Ext(fi.Name()) != ".go" {
+			continue
+		}
+		if ok, err := bctx.MatchFile(testSuitePath, fi.Name()); err != nil || !ok {
+			continue
+		}
+		f, err := parser.ParseFile(fs, filepath.Join(testSuitePath, fi.Name()), nil, 0)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if !strings.HasSuffix(f.Name.Name, "_test") {
+			files = append(files, f)
 		}
-		names = append(names, benchNameRE.FindAllString(string(bs), -1)...)
+	}
+	if len(files) == 0 {
+		return nil
 	}
 
-	return names, nil
+	// collect errors unrelated to a solution
+	errs := []string{}
+	conf := types.Config{
+		Importer: importer.Default(),
+		Error: func(err error) {
+			if te, ok := err.(types.Error); ok &&
+				(strings.HasPrefix(te.Msg, "undefined: ") || strings.HasPrefix(te.Msg, "undeclared name: ")) {
+				return
+			}
+			errs = append(errs, err.Error())
+		},
+	}
+	// errors are collected by the handler
+	conf.Check(files[0].Name.Name, fs, files, nil) //nolint:errcheck
+	if len(errs) != 0 {
+		return fmt.Errorf("test suite %s doesn't compile: %s", testSuitePath, strings.Join(errs, "; "))
+	}
+	return nil
 }
 
-// runBench runs benchmarks matching pattern in a given dir.
-func runBench(dirPath, pattern string) (bstats map[string]*benchStats, err error) {
-	// default pattern
-	if pattern == "" {
-		pattern = "."
+// flagHeavyAllocs tags solutions with allocs above maxAllocs or above median allocs multiplied by ratio.
+// Negative maxAllocs and non-positive ratio disable corresponding checks.
+func flagHeavyAllocs(sstats []*solutionStats, benchName string, maxAllocs int64, ratio float64) {
+	// collect known allocs
+	allocs := []int64{}
+	for _, st := range sstats {
+		if bst := st.bstats[benchName]; bst != nil && bst.allocs != -1 {
+			allocs = append(allocs, bst.allocs)
+		}
 	}
-
-	// run benchmarks with tests
-	out, err := runCmd("go", dirPath, "test", "-bench", pattern, "-benchmem")
-	if err != nil {
+	if len(allocs) == 0 {
 		return
 	}
 
-	// extract stats
-	lines := benchStatsRE.FindAllString(out, -1)
-	if len(lines) == 0 {
-		err = errors.New("no benchmarks")
+	// find median
+	sort.Slice(allocs, func(i, j int) bool { return allocs[i] < allocs[j] })
+	median := float64(allocs[len(allocs)/2])
+	if len(allocs)%2 == 0 {
+		median = float64(allocs[len(allocs)/2-1]+allocs[len(allocs)/2]) / 2
+	}
+
+	// tag heavy ones
+	for _, st := range sstats {
+		bst := st.bstats[benchName]
+		if bst == nil || bst.allocs == -1 {
+			continue
+		}
+		if (maxAllocs >= 0 && bst.allocs > maxAllocs) ||
+			(ratio > 0 && float64(bst.allocs) > median*ratio) {
+			bst.tag("ALLOCS")
+		}
+	}
+}
+
+// flagFailedTests tags solutions with failed tests, their stats are suspect.
+func flagFailedTests(sstats []*solutionStats, benchName string) {
+	for _, st := range sstats {
+		if bst := st.bstats[benchName]; bst != nil && len(st.failed) != 0 {
+			bst.tag("TESTFAIL")
+		}
+	}
+}
+
+// flagSuspect tags solutions with time below minNs as likely eliminated by compiler as dead code.
+func flagSuspect(sstats []*solutionStats, benchName string, minNs float64) {
+	if minNs <= 0 {
 		return
 	}
-	bstats = make(map[string]*benchStats, len(lines))
+	for _, st := range sstats {
+		if bst := st.bstats[benchName]; bst != nil && bst.time < minNs {
+			bst.tag("SUSPECT")
+		}
+	}
+}
 
-	for _, l := range lines {
-		st := &benchStats{
-			throughput: -1,
-			mem:        -1,
-			allocs:     -1,
+// flagFlaky tags solutions with coefficient of variation of time samples above threshold.
+func flagFlaky(sstats []*solutionStats, benchName string, threshold float64) {
+	if threshold <= 0 {
+		return
+	}
+	for _, st := range sstats {
+		if bst := st.bstats[benchName]; bst != nil && bst.timeCV() > threshold {
+			bst.tag("FLAKY")
 		}
+	}
+}
 
-		// benchmark name
-		name := benchNameRE.FindString(l)
-		// time
-		if ms := benchTimeRE.FindStringSubmatch(l); ms != nil {
-			st.time, err = strconv.ParseFloat(ms[1], 64)
-			if err != nil {
-				return
+// getBenchNames looks for declarations of benchmark functions in test files of given dirs
+// and in solution files of a solutions dir unless it's empty.
+// Test files excluded by build constraints with build tags set by flags are skipped like go test does,
+// solution files failed to parse are skipped too since they fail to bench anyway.
+// Each name is returned once in order of first appearance.
+// All nested dirs are ignored except ones of nested solutions layout.
+func getBenchNames(solutionsDir string, dirPaths ...string) (names []string, err error) {
+	seen := make(map[string]struct{})
+	fs := token.NewFileSet()
+	addNames := func(f *ast.File, fp string) {
+		for _, d := range f.Decls {
+			n := benchFuncName(d)
+			if _, ok := seen[n]; n != "" && !ok {
+				vlogf("benchmark %s found in %s", n, fp)
+				seen[n] = struct{}{}
+				names = append(names, n)
 			}
-		} else {
-			panic("no time data")
 		}
+	}
 
-		// optional throughput
-		if ms := benchThroughputRE.FindStringSubmatch(l); ms != nil {
-			st.throughput, err = strconv.ParseFloat(ms[1], 64)
-			if err != nil {
-				return
-			}
+	bctx := build.Default
+	bctx.BuildTags = buildTags()
+	for _, dp := range dirPaths {
+		fis, err := ioutil.ReadDir(dp)
+		if err != nil {
+			return nil, err
 		}
 
-		// optional mem
-		if ms := benchMemRE.FindStringSubmatch(l); ms != nil {
-			st.mem, err = strconv.ParseInt(ms[1], 10, 64)
-			if err != nil {
-				return
+		for _, fi := range fis {
+			if !regular(fi) || !strings.HasSuffix(fi.Name(), "_test.go") {
+				continue
 			}
-			st.allocs, err = strconv.ParseInt(ms[2], 10, 64)
+			fp := filepath.Join(dp, fi.Name())
+			if ok, err := bctx.MatchFile(dp, fi.Name()); err != nil {
+				return nil, err
+			} else if !ok {
+				vlogf("test file %s is excluded by build constraints", fp)
+				continue
+			}
+
+			// parse each test file
+			f, err := parser.ParseFile(fs, fp, nil, 0)
 			if err != nil {
-				return
+				return nil, err
 			}
+			addNames(f, fp)
+		}
+	}
+
+	if solutionsDir == "" {
+		return names, nil
+	}
+	fnames, err := exercism.ListSolutionFiles(solutionsDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, fn := range fnames {
+		fp := filepath.Join(solutionsDir, fn)
+		f, err := parser.ParseFile(fs, fp, nil, 0)
+		if err != nil {
+			vlogf("solution file %s is skipped: %v", fp, err)
+			continue
 		}
-		bstats[name] = st
+		addNames(f, fp)
 	}
 
-	return bstats, nil
+	return names, nil
+}
+
+// buildTags returns build tags set by flags.
+func buildTags() (tags []string) {
+	for _, t := range strings.Split(tagsFlag, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// benchFuncName returns a name of benchmark function declared like go test expects
+// (func BenchmarkXxx(b *testing.B)) or an empty string for other declarations.
+func benchFuncName(d ast.Decl) string {
+	fd, ok := d.(*ast.FuncDecl)
+	if !ok || fd.Recv != nil || !isBenchName(fd.Name.Name) {
+		return ""
+	}
+	ps := fd.Type.Params.List
+	if len(ps) != 1 || len(ps[0].Names) > 1 || fd.Type.Results != nil {
+		return ""
+	}
+	star, ok := ps[0].Type.(*ast.StarExpr)
+	if !ok {
+		return ""
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "B" {
+		return ""
+	}
+	if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "testing" {
+		return ""
+	}
+	return fd.Name.Name
+}
+
+// isBenchName checks a function name is Benchmark followed by nothing or a non-lowercase letter.
+func isBenchName(name string) bool {
+	if !strings.HasPrefix(name, "Benchmark") {
+		return false
+	}
+	rest := name[len("Benchmark"):]
+	if rest == "" {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return !unicode.IsLower(r)
+}
+
+// getToolchains returns toolchains set by flags or go from PATH.
+func getToolchains(ctx context.Context) (tcs []*exercism.Toolchain, err error) {
+	bins := goBinsFlag
+	if len(bins) == 0 {
+		bins = stringsFlag{"go"}
+	}
+
+	for _, b := range bins {
+		tc, err := exercism.GetToolchain(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+		tcs = append(tcs, tc)
+	}
+	return tcs, nil
+}
+
+// toolchainLabels returns labels of toolchains in results and logs, which are their versions
+// followed by #<n> of a toolchain in -go flags if several ones report the same version.
+func toolchainLabels(tcs []*exercism.Toolchain) []string {
+	counts := make(map[string]int, len(tcs))
+	for _, tc := range tcs {
+		counts[tc.Version]++
+	}
+	labels := make([]string, len(tcs))
+	for i, tc := range tcs {
+		labels[i] = tc.Version
+		if counts[tc.Version] > 1 {
+			labels[i] += "#" + strconv.Itoa(i+1)
+		}
+	}
+	return labels
+}
+
+// benchMaxProcs returns GOMAXPROCS of benchmarks, 0 if it's inherited.
+// GOMAXPROCS variable set by -env flag overrides -bench-mp flag since it's set after it.
+func benchMaxProcs() int {
+	n := benchMaxProcsFlag
+	for _, e := range envFlag {
+		if v := strings.TrimPrefix(e, "GOMAXPROCS="); v != e {
+			// the value is checked with flags
+			n, _ = strconv.Atoi(v)
+		}
+	}
+	return n
+}
+
+// runBench runs benchmarks matching pattern in a given dir with a given go binary.
+// out contains raw combined output of go test even if it has failed.
+// Stats of benchmarks completed before a failure are returned with *exercism.PartialError.
+func runBench(ctx context.Context, goBin, dirPath, pattern string) (bstats map[string]*benchStats, out string, err error) {
+	lbstats, out, err := exercism.RunBench(ctx, dirPath, exercism.BenchOptions{
+		GoBin:     goBin,
+		Pattern:   pattern,
+		Mem:       benchMemFlag,
+		Count:     countFlag,
+		Benchtime: benchtimeFlag,
+		Tags:      buildTags(),
+		RunTests:  runTestsFlag,
+		MaxProcs:  benchMaxProcsFlag,
+		Env:       envFlag,
+		Reducer:   reduceFlag,
+	})
+	if lbstats == nil {
+		return nil, out, err
+	}
+
+	bstats = make(map[string]*benchStats, len(lbstats))
+	for bn, lbst := range lbstats {
+		bstats[bn] = fromLibBenchStats(lbst)
+	}
+	return bstats, out, err
 }
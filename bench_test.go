@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/avegner/exercism-bench/exercism"
+)
+
+// testBench returns bench stats w/o throughput and mem stats.
+func testBench(time float64) *benchStats {
+	return &benchStats{time: time, throughput: -1, mem: -1, allocs: -1}
+}
+
+// names returns names of solutions in order.
+func names(sstats []*solutionStats) (ns []string) {
+	for _, st := range sstats {
+		ns = append(ns, st.name)
+	}
+	return ns
+}
+
+func TestSortSolutionStatsByBenchMissing(t *testing.T) {
+	sstats := []*solutionStats{
+		{name: "none1", bstats: map[string]*benchStats{"BenchmarkOther": testBench(1)}},
+		{name: "slow", bstats: map[string]*benchStats{"BenchmarkX": testBench(30)}},
+		{name: "none2", bstats: map[string]*benchStats{}},
+		{name: "fast", bstats: map[string]*benchStats{"BenchmarkX": testBench(10)}},
+		{name: "none3"},
+	}
+
+	sortSolutionStatsByBench(sstats, "BenchmarkX", sortKeyNames)
+	got := strings.Join(names(sstats), " ")
+	if want := "fast slow none1 none2 none3"; got != want {
+		t.Errorf("order = %s, want %s", got, want)
+	}
+}
+
+func TestSortSolutionStatsByBenchThroughput(t *testing.T) {
+	bench := func(time, throughput float64) map[string]*benchStats {
+		bst := testBench(time)
+		bst.throughput = throughput
+		return map[string]*benchStats{"BenchmarkX": bst}
+	}
+	sstats := []*solutionStats{
+		{name: "slow-fast", size: 1, bstats: bench(20, 500)},
+		{name: "low", size: 1, bstats: bench(10, 50)},
+		{name: "high", size: 2, bstats: bench(10, 100)},
+	}
+
+	// time goes first, then higher throughput despite larger size
+	sortSolutionStatsByBench(sstats, "BenchmarkX", sortKeyNames)
+	got := strings.Join(names(sstats), " ")
+	if want := "high low slow-fast"; got != want {
+		t.Errorf("order = %s, want %s", got, want)
+	}
+}
+
+func TestPrintBenchReportMissing(t *testing.T) {
+	defer func(v bool) { colorEnabled = v }(colorEnabled)
+	colorEnabled = false
+
+	sstats := []*solutionStats{
+		{name: "none", bstats: map[string]*benchStats{}},
+		{name: "slow", bstats: map[string]*benchStats{"BenchmarkX": testBench(30)}},
+		{name: "fast", bstats: map[string]*benchStats{"BenchmarkX": testBench(10)}},
+	}
+	buf := &bytes.Buffer{}
+	ranks := printBenchReport(log.New(buf, "", 0), sstats, "BenchmarkX", len(sstats))
+
+	if len(ranks) != 2 || ranks["fast"] != 1 || ranks["slow"] != 2 {
+		t.Errorf("ranks = %v, want fast 1 and slow 2", ranks)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "2/3 reported") {
+		t.Errorf("no reported count in:\n%s", out)
+	}
+	if !strings.Contains(out, "no result:\n- none\n") {
+		t.Errorf("no missing solution in:\n%s", out)
+	}
+}
+
+// writeBenchFile writes a test file of package ex with benchmarks of given names.
+func writeBenchFile(t *testing.T, path string, names ...string) {
+	src := "package ex\n\nimport \"testing\"\n"
+	for _, n := range names {
+		src += "\nfunc " + n + "(b *testing.B) {}\n"
+	}
+	if err := ioutil.WriteFile(path, []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetBenchNamesDedup(t *testing.T) {
+	dir, extra := t.TempDir(), t.TempDir()
+	writeBenchFile(t, filepath.Join(dir, "a_test.go"), "BenchmarkB", "BenchmarkA")
+	writeBenchFile(t, filepath.Join(dir, "b_test.go"), "BenchmarkC", "BenchmarkA", "BenchmarkB")
+	writeBenchFile(t, filepath.Join(extra, "c_test.go"), "BenchmarkC", "BenchmarkD")
+
+	names, err := getBenchNames("", dir, extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"BenchmarkB", "BenchmarkA", "BenchmarkC", "BenchmarkD"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+}
+
+func TestGetBenchNamesDecls(t *testing.T) {
+	dir := t.TempDir()
+	src := `package ex
+
+import "testing"
+
+// BenchmarkComment is mentioned in a comment only.
+var name = "BenchmarkString"
+
+func BenchmarkDecl(b *testing.B) {
+	BenchmarkHelper(b, "BenchmarkString")
+}
+
+func BenchmarkHelper(b *testing.B, s string) {}
+
+func Benchmarklower(b *testing.B) {}
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "ex_test.go"), []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// non-test files of a dir aren't go test sources
+	writeBenchFile(t, filepath.Join(dir, "ex.go"), "BenchmarkNonTest")
+
+	names, err := getBenchNames("", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"BenchmarkDecl"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+}
+
+func TestGetBenchNamesSolutions(t *testing.T) {
+	suite, sols := t.TempDir(), t.TempDir()
+	writeBenchFile(t, filepath.Join(suite, "ex_test.go"), "BenchmarkA")
+	writeBenchFile(t, filepath.Join(sols, "0123456789abcdef0123456789abcdef-alice.go"), "BenchmarkA", "BenchmarkSolution")
+	if err := os.MkdirAll(filepath.Join(sols, "bob"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeBenchFile(t, filepath.Join(sols, "bob", "bob.go"), "BenchmarkNested")
+	if err := ioutil.WriteFile(filepath.Join(sols, "broken.go"), []byte("package ex\nfunc {"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := getBenchNames(sols, suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"BenchmarkA", "BenchmarkSolution", "BenchmarkNested"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+}
+
+func TestMergeRoundsFailed(t *testing.T) {
+	round := func(name string, time float64, failed ...string) *solutionStats {
+		bst := testBench(time)
+		bst.samples = []float64{time}
+		return &solutionStats{name: name, failed: failed, bstats: map[string]*benchStats{"BenchmarkA": bst}}
+	}
+	merged := mergeRounds([]*solutionStats{
+		round("a", 10),
+		round("b", 20, "TestX"),
+		round("a", 12, "TestY"),
+		round("b", 22, "TestZ", "TestX"),
+		round("a", 11, "TestX", "TestY"),
+	})
+	if got := names(merged); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("merged solutions = %v, want [a b]", got)
+	}
+	if want := []string{"TestY", "TestX"}; !reflect.DeepEqual(merged[0].failed, want) {
+		t.Errorf("failed tests of a = %v, want %v", merged[0].failed, want)
+	}
+	if want := []string{"TestX", "TestZ"}; !reflect.DeepEqual(merged[1].failed, want) {
+		t.Errorf("failed tests of b = %v, want %v", merged[1].failed, want)
+	}
+	if bst := merged[0].bstats["BenchmarkA"]; len(bst.samples) != 3 {
+		t.Errorf("samples of a = %v, want 3 rounds", bst.samples)
+	}
+}
+
+func TestBenchMaxProcs(t *testing.T) {
+	defer func(n int, env stringsFlag) { benchMaxProcsFlag, envFlag = n, env }(benchMaxProcsFlag, envFlag)
+
+	cases := []struct {
+		mp   int
+		env  stringsFlag
+		want int
+	}{
+		{1, nil, 1},
+		{0, nil, 0},
+		{1, stringsFlag{"GOGC=off"}, 1},
+		// the last variable takes effect like in process environment
+		{1, stringsFlag{"GOMAXPROCS=4"}, 4},
+		{0, stringsFlag{"GOMAXPROCS=2", "GOMAXPROCS=8"}, 8},
+	}
+	for _, c := range cases {
+		benchMaxProcsFlag, envFlag = c.mp, c.env
+		if got := benchMaxProcs(); got != c.want {
+			t.Errorf("benchMaxProcs() with -bench-mp %d -env %v = %d, want %d", c.mp, c.env, got, c.want)
+		}
+	}
+}
+
+func TestToolchainLabels(t *testing.T) {
+	tcs := []*exercism.Toolchain{
+		{Bin: "/a/go", Version: "go1.21"},
+		{Bin: "go1.20", Version: "go1.20"},
+		{Bin: "/b/go", Version: "go1.21"},
+	}
+	// only repeated versions are told apart by positions of binaries
+	got := toolchainLabels(tcs)
+	if want := []string{"go1.21#1", "go1.20", "go1.21#3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("labels = %v, want %v", got, want)
+	}
+}
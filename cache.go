@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+type codeMetrics struct {
+	Size uint `json:"size"`
+}
+
+// sizeCache keeps code metrics by source code content hash.
+type sizeCache struct {
+	path    string
+	mx      sync.Mutex
+	metrics map[string]*codeMetrics
+}
+
+// loadSizeCache loads cache from a given file.
+// An empty cache is returned if the file doesn't exist.
+func loadSizeCache(path string) (*sizeCache, error) {
+	c := &sizeCache{
+		path:    path,
+		metrics: make(map[string]*codeMetrics),
+	}
+
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(bs, &c.metrics); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// save stores cache to its file.
+func (c *sizeCache) save() error {
+	c.mx.Lock()
+	bs, err := json.Marshal(c.metrics)
+	c.mx.Unlock()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, bs, 0600)
+}
+
+// getCodeSize returns a cached code size or calculates and caches a new one.
+func (c *sizeCache) getCodeSize(sourceFilePath string) (size uint, err error) {
+	bs, err := ioutil.ReadFile(sourceFilePath)
+	if err != nil {
+		return
+	}
+	// sizes depend on metric and normalization too
+	sum := sha256.Sum256(bs)
+	hash := hex.EncodeToString(sum[:]) + "-" + sizeMetricFlag
+	if normalizeFlag {
+		hash += "-normalized"
+	}
+
+	// look up
+	c.mx.Lock()
+	m, ok := c.metrics[hash]
+	c.mx.Unlock()
+	if ok {
+		return m.Size, nil
+	}
+
+	// calculate and store
+	size, err = countCodeSize(sourceFilePath, bs)
+	if err != nil {
+		return
+	}
+	c.mx.Lock()
+	c.metrics[hash] = &codeMetrics{
+		Size: size,
+	}
+	c.mx.Unlock()
+	return size, nil
+}
+
+// page cache hit and miss counters
+var pageCacheHits, pageCacheMisses int64
+
+// pageCachePath returns a path of cached page with a given URL.
+func pageCachePath(cfg *config, urlv string) string {
+	sum := sha256.Sum256([]byte(urlv))
+	return filepath.Join(cfg.dir, "page-cache", hex.EncodeToString(sum[:])+".html")
+}
+
+// readCachedPage returns content of a page from the cache if it's enabled.
+func readCachedPage(cfg *config, urlv string) (content string, ok bool) {
+	if !pageCacheFlag {
+		return "", false
+	}
+	bs, err := ioutil.ReadFile(pageCachePath(cfg, urlv))
+	if err != nil {
+		atomic.AddInt64(&pageCacheMisses, 1)
+		return "", false
+	}
+	atomic.AddInt64(&pageCacheHits, 1)
+	return string(bs), true
+}
+
+// writeCachedPage stores content of a page to the cache if it's enabled.
+func writeCachedPage(cfg *config, urlv, content string) {
+	if !pageCacheFlag {
+		return
+	}
+	p := pageCachePath(cfg, urlv)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		mlog.Printf("page cache write error: %v", err)
+		return
+	}
+	if err := writeFileAtomic(p, []byte(content), 0600); err != nil {
+		mlog.Printf("page cache write error: %v", err)
+	}
+}
@@ -1,22 +1,144 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/avegner/exercism-bench/exercism"
 )
 
-var httpClient = http.Client{
-	Timeout: 5 * time.Second,
+// httpTimeout is a default timeout of HTTP requests.
+const httpTimeout = 5 * time.Second
+
+var (
+	errOffline       = errors.New("network access is disabled in offline mode")
+	errLoginRequired = errors.New("login required; export fresh cookies of exercism.io from a browser to cookie jar file")
+)
+
+var (
+	hostGates   = make(map[string]chan struct{})
+	hostGatesMx sync.Mutex
+)
+
+// jitter is a source of backoff delays, tests replace it with a seeded one.
+var (
+	jitter   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterMx sync.Mutex
+)
+
+// acquireHost blocks until a request to the host of urlv is allowed by per host limit or ctx is done.
+// The returned function releases the host.
+func acquireHost(ctx context.Context, urlv string) (release func(), err error) {
+	if perHostFlag <= 0 {
+		return func() {}, nil
+	}
+	u, err := url.Parse(urlv)
+	if err != nil {
+		return nil, err
+	}
+
+	hostGatesMx.Lock()
+	gate, ok := hostGates[u.Host]
+	if !ok {
+		gate = make(chan struct{}, perHostFlag)
+		hostGates[u.Host] = gate
+	}
+	hostGatesMx.Unlock()
+
+	select {
+	case gate <- struct{}{}:
+	default:
+		vlogf("download of %s waits for %s host", urlv, u.Host)
+		select {
+		case gate <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return func() { <-gate }, nil
 }
 
-//nolint:gosec
-func getSolutionPage(uuid string, params map[string]string) (content string, urlv string, err error) {
+// configureTLS sets up transport of HTTP client with a custom root CA or w/o certificate verification
+// if it's requested by flags.
+func configureTLS(client *http.Client) error {
+	if caCertFlag == "" && !insecureFlag {
+		return nil
+	}
+
+	tc := &tls.Config{}
+	if caCertFlag != "" {
+		pem, err := ioutil.ReadFile(caCertFlag)
+		if err != nil {
+			return err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", caCertFlag)
+		}
+		tc.RootCAs = pool
+	}
+	if insecureFlag {
+		mlog.Printf("WARNING: TLS certificate verification is disabled, connections can be intercepted")
+		tc.InsecureSkipVerify = true //nolint:gosec
+	}
+
+	client.Transport = &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     tc,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	return nil
+}
+
+// statusError is returned for unexpected HTTP status codes.
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status code %q", e.status)
+}
+
+// isNotFound checks if err is caused by 404 status code.
+func isNotFound(err error) bool {
+	se, ok := err.(*statusError)
+	return ok && se.code == http.StatusNotFound
+}
+
+// getSolutionPage downloads a solution page or a solution groups page for an empty uuid.
+// Solution pages are small but numerous, so they have a separate timeout.
+func getSolutionPage(ctx context.Context, cfg *config, uuid string, params map[string]string) (content string, urlv string, err error) {
+	timeout := pageTimeoutFlag
+	if uuid != "" {
+		timeout = solTimeoutFlag
+	}
+	return getPage(ctx, cfg, timeout, exercism.SolutionsURL(cfg.addr, trackLang, cfg.exercise, uuid), params)
+}
+
+func getExercisesPage(ctx context.Context, cfg *config, params map[string]string) (content string, urlv string, err error) {
+	return getPage(ctx, cfg, pageTimeoutFlag, strings.Join([]string{cfg.addr, "tracks", trackLang, "exercises"}, "/"), params)
+}
+
+// getPage downloads a page retrying on network errors and server failures.
+// Each attempt is limited by timeout (HTTP timeout if 0).
+// A cached page is returned instead if page cache is enabled.
+func getPage(ctx context.Context, cfg *config, timeout time.Duration, baseURL string, params map[string]string) (content string, urlv string, err error) {
 	// form URL
-	urlv = strings.Join([]string{exercismAddr, "tracks", trackLang, "exercises", exercise, "solutions", uuid}, "/")
+	urlv = baseURL
 	// form params
 	if len(params) > 0 {
 		vs := url.Values{}
@@ -25,6 +147,40 @@ func getSolutionPage(uuid string, params map[string]string) (content string, url
 		}
 		urlv += "?" + vs.Encode()
 	}
+
+	if c, ok := readCachedPage(cfg, urlv); ok {
+		return c, urlv, nil
+	}
+	if offlineFlag {
+		return "", urlv, errOffline
+	}
+
+	for attempt := 0; ; attempt++ {
+		content, err = fetchPage(ctx, cfg.client, timeout, urlv)
+		if err == nil {
+			writeCachedPage(cfg, urlv, content)
+			return content, urlv, nil
+		}
+		if attempt >= httpRetriesFlag || !retryable(err) || ctx.Err() != nil {
+			return content, urlv, err
+		}
+		d := backoff(attempt)
+		vlogf("download of %s failed: %v; retry %d / %d in %v", urlv, err, attempt+1, httpRetriesFlag, d)
+		if err = sleep(ctx, d); err != nil {
+			return "", urlv, err
+		}
+	}
+}
+
+//nolint:gosec
+func fetchPage(ctx context.Context, client *http.Client, timeout time.Duration, urlv string) (content string, err error) {
+	// limit concurrent requests to the host
+	release, err := acquireHost(ctx, urlv)
+	if err != nil {
+		return
+	}
+	defer release()
+
 	// create request
 	req, err := http.NewRequest("GET", urlv, nil)
 	if err != nil {
@@ -32,14 +188,27 @@ func getSolutionPage(uuid string, params map[string]string) (content string, url
 	}
 
 	// do request
-	resp, err := httpClient.Do(req)
+	if timeout <= 0 {
+		timeout = httpTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	resp, err := client.Do(req.WithContext(ctx))
 	if err != nil {
 		return
 	}
 	defer resp.Body.Close()
 
+	// unauthorized requests are redirected to sign in page
+	if resp.StatusCode == http.StatusUnauthorized || strings.Contains(resp.Request.URL.Path, "/users/sign_in") {
+		err = errLoginRequired
+		return
+	}
 	if resp.StatusCode != http.StatusOK {
-		err = fmt.Errorf("status code %q", resp.Status)
+		err = &statusError{
+			code:   resp.StatusCode,
+			status: resp.Status,
+		}
 		return
 	}
 
@@ -47,5 +216,42 @@ func getSolutionPage(uuid string, params map[string]string) (content string, url
 	if err != nil {
 		return
 	}
-	return string(bs), urlv, nil
+	return string(bs), nil
+}
+
+// retryable checks if a request failed with err can succeed later.
+func retryable(err error) bool {
+	if err == errLoginRequired {
+		return false
+	}
+	if se, ok := err.(*statusError); ok {
+		return se.code >= http.StatusInternalServerError || se.code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// sleep pauses for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backoff returns a random delay up to exponentially growing limit (full jitter).
+func backoff(attempt int) time.Duration {
+	limit := backoffMaxFlag
+	if attempt < 32 && backoffBaseFlag<<uint(attempt) < limit {
+		limit = backoffBaseFlag << uint(attempt)
+	}
+	if limit <= 0 {
+		return 0
+	}
+	jitterMx.Lock()
+	defer jitterMx.Unlock()
+	return time.Duration(jitter.Int63n(int64(limit)) + 1)
 }
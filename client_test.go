@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// cancelAfter returns a context canceled after a given delay.
+func cancelAfter(t *testing.T, d time.Duration) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	time.AfterFunc(d, cancel)
+	return ctx
+}
+
+// checkPrompt fails if a canceled call took too long or returned no error.
+func checkPrompt(t *testing.T, start time.Time, err error) {
+	t.Helper()
+	if err == nil {
+		t.Error("no error after cancel")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("return took %v after cancel", d)
+	}
+}
+
+func TestGetSolutionPageCancel(t *testing.T) {
+	// the server never responds until the request is canceled
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(30 * time.Second):
+		}
+	}))
+	defer srv.Close()
+	cfg := &config{addr: srv.URL, exercise: "ex", client: &http.Client{}}
+
+	start := time.Now()
+	_, _, err := getSolutionPage(cancelAfter(t, 100*time.Millisecond), cfg, "", nil)
+	checkPrompt(t, start, err)
+}
+
+func TestGetPageCancelBackoff(t *testing.T) {
+	defer func(r int, b time.Duration) { httpRetriesFlag, backoffBaseFlag = r, b }(httpRetriesFlag, backoffBaseFlag)
+	httpRetriesFlag, backoffBaseFlag = 5, 20*time.Second
+
+	// failures are retried after a long backoff
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	cfg := &config{addr: srv.URL, exercise: "ex", client: &http.Client{}}
+
+	start := time.Now()
+	_, _, err := getSolutionPage(cancelAfter(t, 100*time.Millisecond), cfg, "", nil)
+	checkPrompt(t, start, err)
+	if err != context.Canceled {
+		t.Errorf("error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	defer func(r *rand.Rand) { jitter = r }(jitter)
+	defer func(b, m time.Duration) { backoffBaseFlag, backoffMaxFlag = b, m }(backoffBaseFlag, backoffMaxFlag)
+	backoffBaseFlag, backoffMaxFlag = 100*time.Millisecond, time.Second
+
+	delays := func() (ds []time.Duration) {
+		jitter = rand.New(rand.NewSource(1))
+		for attempt := 0; attempt < 8; attempt++ {
+			ds = append(ds, backoff(attempt))
+		}
+		return ds
+	}
+	ds := delays()
+	for attempt, d := range ds {
+		limit := backoffBaseFlag << uint(attempt)
+		if limit > backoffMaxFlag {
+			limit = backoffMaxFlag
+		}
+		if d <= 0 || d > limit {
+			t.Errorf("delay of attempt %d = %v, want (0, %v]", attempt, d, limit)
+		}
+	}
+	// the same seed gives the same delays
+	if again := delays(); !reflect.DeepEqual(again, ds) {
+		t.Errorf("delays = %v, want %v", again, ds)
+	}
+}
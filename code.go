@@ -1,172 +1,173 @@
 package main
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"go/ast"
+	"go/format"
 	"go/parser"
 	"go/token"
-	"html"
 	"io/ioutil"
-	"regexp"
+	"runtime"
 	"strings"
-	"unicode"
-)
-
-const (
-	testSuiteStartPattern    = "<div class='pane pane-2 test-suite'>"
-	testSuiteEndPattern      = "</div>"
-	codeStartPattern         = "<code class='language-go'>"
-	codeEndPattern           = "</code>"
-	solutionCodeStartPattern = "<pre class='line-numbers solution-code'>" + codeStartPattern
-	solutionCodeEndPattern   = codeEndPattern + "</pre>"
-	testFileNameStartPattern = "<h3>"
-	testFileNameEndPattern   = "</h3>"
-)
+	"sync"
 
-var (
-	authorRE = regexp.MustCompile("Avatar of (([[:word:]]|-)+)")
+	"github.com/avegner/exercism-bench/exercism"
 )
 
-var (
-	errNoSolutionCode = errors.New("no solution code")
-	errNoAuthorName   = errors.New("no author name")
-	errNoTestSuite    = errors.New("no test suite")
-)
-
-type codeRange struct {
-	start int
-	end   int
-}
-
-type codeRanges []*codeRange
-
-func (ranges *codeRanges) include(offset int) bool {
-	for _, r := range *ranges {
-		if offset >= r.start && offset < r.end {
-			return true
-		}
-	}
-	return false
-}
-
-func (ranges *codeRanges) add(start, end int) {
-	*ranges = append(*ranges, &codeRange{
-		start: start,
-		end:   end,
-	})
-}
-
 // getCodeSize returns number of symbols in code w/o white spaces and comments.
 func getCodeSize(sourceFilePath string) (size uint, err error) {
 	bs, err := ioutil.ReadFile(sourceFilePath)
 	if err != nil {
 		return
 	}
+	return countCodeSize(sourceFilePath, bs)
+}
 
-	// exclude comments and ignore white spaces in string and char literals
-	var (
-		exclude, ignore codeRanges
-	)
+// countCodeSize returns number of symbols in source code w/o white spaces and comments
+// or number of tokens w/o comments if tokens size metric is set.
+// Source code is gofmt-ed first in normalize mode.
+// File name is used only for error messages.
+func countCodeSize(fileName string, bs []byte) (size uint, err error) {
+	return exercism.CodeSize(fileName, bs, sizeMetricFlag, normalizeFlag)
+}
 
-	// parse source code
+// verifySolutionFile checks a stored solution file is non-empty and parses as go source code.
+func verifySolutionFile(path string) error {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(bs)) == 0 {
+		return errors.New("empty file")
+	}
+	_, err = parser.ParseFile(token.NewFileSet(), path, bs, 0)
+	return err
+}
+
+// canonicalCode returns source code w/o comments in gofmt style with local identifiers except fields renamed
+// in order of appearance, so solutions differing only in these aspects have the same canonical code.
+func canonicalCode(fileName string, bs []byte) (code string, err error) {
 	fs := token.NewFileSet()
-	f, err := parser.ParseFile(fs, sourceFilePath, bs, parser.ParseComments)
+	f, err := parser.ParseFile(fs, fileName, bs, 0)
 	if err != nil {
 		return
 	}
 
-	// find all comments, string and char literals
+	// struct fields and interface methods are left alone since their selectors aren't resolved
+	members := make(map[*ast.Field]bool)
 	ast.Inspect(f, func(n ast.Node) bool {
-		switch v := n.(type) {
-		case *ast.Comment:
-			exclude.add(fs.Position(v.Pos()).Offset, fs.Position(v.End()).Offset)
-		case *ast.BasicLit:
-			if v.Kind == token.STRING || v.Kind == token.CHAR {
-				ignore.add(fs.Position(v.Pos()).Offset, fs.Position(v.End()).Offset)
+		switch t := n.(type) {
+		case *ast.StructType:
+			for _, fd := range t.Fields.List {
+				members[fd] = true
+			}
+		case *ast.InterfaceType:
+			for _, fd := range t.Methods.List {
+				members[fd] = true
 			}
 		}
 		return true
 	})
 
-	// count only relevant code symbols
-	for i, r := range string(bs) {
-		if exclude.include(i) {
-			continue
+	// rename objects not declared at file scope
+	names := make(map[*ast.Object]string)
+	ast.Inspect(f, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || id.Obj == nil || f.Scope.Lookup(id.Name) == id.Obj {
+			return true
+		}
+		if fd, ok := id.Obj.Decl.(*ast.Field); ok && members[fd] {
+			return true
 		}
-		if ignore.include(i) || !unicode.IsSpace(r) {
-			size++
+		name, ok := names[id.Obj]
+		if !ok {
+			name = fmt.Sprintf("v%d", len(names))
+			names[id.Obj] = name
 		}
+		id.Name = name
+		return true
+	})
+
+	buf := bytes.Buffer{}
+	if err = format.Node(&buf, token.NewFileSet(), f); err != nil {
+		return
 	}
-	return size, nil
+	return buf.String(), nil
 }
 
-func extractSolutionCode(solutionPage string) (code, author string, err error) {
-	// extract author name
-	ms := authorRE.FindStringSubmatch(solutionPage)
-	if ms == nil {
-		return "", "", errNoAuthorName
+// getCodeSizes concurrently calculates code sizes of given source files.
+// Up to GOMAXPROCS files are parsed simultaneously.
+// Cached sizes are reused and new ones are added to the cache if it's not nil.
+func getCodeSizes(sourceFilePaths []string, cache *sizeCache) (sizes map[string]uint, errs map[string]error) {
+	sizes = make(map[string]uint, len(sourceFilePaths))
+	errs = make(map[string]error)
+
+	wg := sync.WaitGroup{}
+	mx := sync.Mutex{}
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+
+	for _, p := range sourceFilePaths {
+		fp := p
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			var (
+				size uint
+				err  error
+			)
+			if cache != nil {
+				size, err = cache.getCodeSize(fp)
+			} else {
+				size, err = getCodeSize(fp)
+			}
+			mx.Lock()
+			if err != nil {
+				errs[fp] = err
+			} else {
+				sizes[fp] = size
+			}
+			mx.Unlock()
+		}()
 	}
-	author = html.UnescapeString(ms[1])
 
-	// extract code
-	m, _ := getFirstMatch(solutionPage, solutionCodeStartPattern, solutionCodeEndPattern)
-	if m == "" {
-		return "", "", errNoSolutionCode
-	}
-	code = html.UnescapeString(m)
+	// wait all goroutines
+	wg.Wait()
 
-	return code, author, nil
+	return sizes, errs
 }
 
-func extractTestSuite(solutionPage string) (suite map[string]string, err error) {
-	// locate test suite
-	ts, _ := getFirstMatch(solutionPage, testSuiteStartPattern, testSuiteEndPattern)
-	if ts == "" {
-		return nil, errNoTestSuite
+func extractSolutionCode(solutionPage string) (code, author string, err error) {
+	code, author, err = exercism.ExtractSolutionCode(solutionPage)
+	if err != nil {
+		return "", "", err
 	}
+	return normalizeLineEndings(code), author, nil
+}
 
-	// extract test files
-	suite = make(map[string]string)
-	for {
-		// locate file name
-		var m string
-		m, ts = getFirstMatch(ts, testFileNameStartPattern, testFileNameEndPattern)
-		if m == "" {
-			if len(suite) == 0 {
-				return nil, errNoTestSuite
-			}
-			break
-		}
-		name := html.UnescapeString(m)
-
-		// locate code
-		m, ts = getFirstMatch(ts, codeStartPattern, codeEndPattern)
-		if m == "" {
-			return nil, errNoTestSuite
-		}
-		code := html.UnescapeString(m)
-
-		// fill in suite
-		suite[name] = code
+func extractTestSuite(solutionPage string) (suite map[string]string, err error) {
+	suite, err = exercism.ExtractTestSuite(solutionPage)
+	if err != nil {
+		return nil, err
+	}
+	for name, code := range suite {
+		suite[name] = normalizeLineEndings(code)
 	}
-
 	return suite, nil
 }
 
-// getFirstMatch looks for a substring with given start and end patterns.
-// match contains the substring excluding patterns or empty string if nothing has been found.
-// out gets the remaining input string after the chunk and the end pattern.
-func getFirstMatch(in, sp, ep string) (match, out string) {
-	sind := strings.Index(in, sp)
-	if sind == -1 {
-		return
-	}
-	sind += len(sp)
-
-	eind := strings.Index(in[sind:], ep)
-	if eind == -1 {
-		return
+// normalizeLineEndings converts CRLF and CR line endings to LF unless raw ones are requested.
+func normalizeLineEndings(code string) string {
+	if keepCRLFFlag {
+		return code
 	}
-
-	return in[sind : sind+eind], in[sind+eind+len(ep):]
+	code = strings.ReplaceAll(code, "\r\n", "\n")
+	return strings.ReplaceAll(code, "\r", "\n")
 }
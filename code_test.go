@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"html"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testSolutionPage returns a solution page with code of an author and test files given as name and code pairs.
+func testSolutionPage(author, code string, files ...string) string {
+	page := "<img alt=\"Avatar of " + author + "\">\n" +
+		"<pre class='line-numbers solution-code'><code class='language-go'>" + html.EscapeString(code) + "</code></pre>\n" +
+		"<div class='pane pane-2 test-suite'>"
+	for i := 0; i+1 < len(files); i += 2 {
+		page += "<h3>" + files[i] + "</h3><code class='language-go'>" + html.EscapeString(files[i+1]) + "</code>"
+	}
+	return page + "</div>\n"
+}
+
+const mixedEndingsCode = "package ex\r\n\r\n// Sum sums.\rfunc Sum(a, b int) int {\r\n\treturn a + b\n}\r\n"
+
+func TestExtractSolutionCodeLineEndings(t *testing.T) {
+	defer func(v bool) { keepCRLFFlag = v }(keepCRLFFlag)
+	page := testSolutionPage("some-one", mixedEndingsCode)
+
+	keepCRLFFlag = false
+	code, author, err := extractSolutionCode(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if author != "some-one" {
+		t.Errorf("author = %q, want some-one", author)
+	}
+	if strings.Contains(code, "\r") {
+		t.Errorf("code has CR: %q", code)
+	}
+	if want := "package ex\n\n// Sum sums.\nfunc Sum(a, b int) int {\n\treturn a + b\n}\n"; code != want {
+		t.Errorf("code = %q, want %q", code, want)
+	}
+	if _, err = parser.ParseFile(token.NewFileSet(), "ex.go", code, parser.AllErrors); err != nil {
+		t.Errorf("normalized code doesn't parse: %v", err)
+	}
+
+	keepCRLFFlag = true
+	if code, _, err = extractSolutionCode(page); err != nil {
+		t.Fatal(err)
+	}
+	if code != mixedEndingsCode {
+		t.Errorf("raw code = %q, want %q", code, mixedEndingsCode)
+	}
+}
+
+func TestExtractTestSuiteLineEndings(t *testing.T) {
+	defer func(v bool) { keepCRLFFlag = v }(keepCRLFFlag)
+	keepCRLFFlag = false
+
+	test := "package ex\r\n\r\nimport \"testing\"\r\rfunc TestSum(t *testing.T) {\n\tif Sum(1, 2) != 3 {\r\n\t\tt.Fail()\r\n\t}\r\n}\r\n"
+	suite, err := extractTestSuite(testSolutionPage("some-one", "package ex\n", "ex_test.go", test))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, ok := suite["ex_test.go"]
+	if !ok || len(suite) != 1 {
+		t.Fatalf("suite = %v, want only ex_test.go", suite)
+	}
+	if strings.Contains(code, "\r") {
+		t.Errorf("test file has CR: %q", code)
+	}
+	if _, err = parser.ParseFile(token.NewFileSet(), "ex_test.go", code, parser.AllErrors); err != nil {
+		t.Errorf("normalized test file doesn't parse: %v", err)
+	}
+}
+
+// writeTestSolutions writes n distinct solution files to dir and returns their paths.
+func writeTestSolutions(tb testing.TB, dir string, n int) (paths []string) {
+	for i := 0; i < n; i++ {
+		fp := filepath.Join(dir, fmt.Sprintf("solution%d.go", i))
+		code := fmt.Sprintf("package ex\n\n// Sum sums.\nfunc Sum(a, b int) int {\n\treturn a + b + %d\n}\n", i)
+		if err := ioutil.WriteFile(fp, []byte(code), 0600); err != nil {
+			tb.Fatal(err)
+		}
+		paths = append(paths, fp)
+	}
+	return paths
+}
+
+func TestGetCodeSizes(t *testing.T) {
+	paths := writeTestSolutions(t, t.TempDir(), 50)
+	broken := filepath.Join(t.TempDir(), "broken.go")
+	if err := ioutil.WriteFile(broken, []byte("package ex\nfunc {"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sizes, errs := getCodeSizes(append(paths, broken), nil)
+	if len(sizes) != len(paths) || len(errs) != 1 || errs[broken] == nil {
+		t.Fatalf("got %d sizes and errors %v, want %d sizes and error of %s", len(sizes), errs, len(paths), broken)
+	}
+	for _, fp := range paths {
+		size, err := getCodeSize(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sizes[fp] != size {
+			t.Errorf("size of %s = %d, want %d", fp, sizes[fp], size)
+		}
+	}
+}
+
+func BenchmarkGetCodeSizes(b *testing.B) {
+	paths := writeTestSolutions(b, b.TempDir(), 500)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, errs := getCodeSizes(paths, nil); len(errs) != 0 {
+			b.Fatal(errs)
+		}
+	}
+}
+
+// readLargePage returns a solution page of large code and test suite.
+func readLargePage(b *testing.B) string {
+	bs, err := ioutil.ReadFile("testdata/large_page.html")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return string(bs)
+}
+
+func BenchmarkExtractSolutionCode(b *testing.B) {
+	page := readLargePage(b)
+	b.SetBytes(int64(len(page)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := extractSolutionCode(page); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExtractTestSuite(b *testing.B) {
+	page := readLargePage(b)
+	b.SetBytes(int64(len(page)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := extractTestSuite(page); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetCodeSize(b *testing.B) {
+	defer func(v string) { sizeMetricFlag = v }(sizeMetricFlag)
+	code, _, err := extractSolutionCode(readLargePage(b))
+	if err != nil {
+		b.Fatal(err)
+	}
+	fp := filepath.Join(b.TempDir(), "solution.go")
+	if err := ioutil.WriteFile(fp, []byte(code), 0600); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, metric := range []string{"symbols", "tokens"} {
+		b.Run(metric, func(b *testing.B) {
+			sizeMetricFlag = metric
+			b.SetBytes(int64(len(code)))
+			for i := 0; i < b.N; i++ {
+				if _, err := getCodeSize(fp); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestCanonicalCodeStructFields(t *testing.T) {
+	const a = `package ex
+
+type counter struct{ total, n int }
+
+func Sum(xs []int) int {
+	type acc struct{ sum int }
+	c, s := counter{total: 0}, acc{}
+	for _, x := range xs {
+		c.total += x
+		s.sum += x
+	}
+	return c.total
+}
+`
+	const b = `package ex
+
+// counter counts.
+type counter struct{ total, n int }
+
+func Sum(vs []int) int {
+	type acc struct{ sum int }
+	k, r := counter{total: 0}, acc{}
+	for _, v := range vs {
+		k.total += v
+		r.sum += v
+	}
+	return k.total
+}
+`
+	ca, err := canonicalCode("a.go", []byte(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := canonicalCode("b.go", []byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ca != cb {
+		t.Errorf("canonical codes differ:\n%s\n%s", ca, cb)
+	}
+	// field declarations keep names of their selectors and keys
+	for _, s := range []string{"struct{ sum int }", "counter{total: 0}", ".total += ", ".sum += "} {
+		if !strings.Contains(ca, s) {
+			t.Errorf("no %q in canonical code:\n%s", s, ca)
+		}
+	}
+}
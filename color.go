@@ -0,0 +1,32 @@
+package main
+
+import "os"
+
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+)
+
+// colorEnabled is set if ANSI colors can be used in log output.
+var colorEnabled = false
+
+// initColor enables colors only for a terminal and if they aren't disabled by a flag or NO_COLOR env variable.
+func initColor() {
+	if noColorFlag || os.Getenv("NO_COLOR") != "" {
+		return
+	}
+	fi, err := os.Stderr.Stat()
+	if err != nil {
+		return
+	}
+	colorEnabled = fi.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize wraps s in ANSI color codes if colors are enabled.
+func colorize(s, color string) string {
+	if !colorEnabled {
+		return s
+	}
+	return color + s + colorReset
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+
+	_ "modernc.org/sqlite" // pure Go SQLite driver
+)
+
+// dbSchema keeps results of all runs, go version is a part of the key to keep results of several toolchains.
+const dbSchema = `CREATE TABLE IF NOT EXISTS results (
+	exercise      TEXT NOT NULL,
+	uuid          TEXT NOT NULL,
+	benchmark     TEXT NOT NULL,
+	run_timestamp TEXT NOT NULL,
+	go_version    TEXT NOT NULL,
+	solution      TEXT NOT NULL,
+	size          INTEGER NOT NULL,
+	time          REAL NOT NULL,
+	throughput    REAL,
+	mem           INTEGER,
+	allocs        INTEGER,
+	PRIMARY KEY (exercise, uuid, benchmark, run_timestamp, go_version)
+)`
+
+const dbUpsert = `INSERT INTO results
+	(exercise, uuid, benchmark, run_timestamp, go_version, solution, size, time, throughput, mem, allocs)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (exercise, uuid, benchmark, run_timestamp, go_version) DO UPDATE SET
+	solution = excluded.solution, size = excluded.size, time = excluded.time,
+	throughput = excluded.throughput, mem = excluded.mem, allocs = excluded.allocs`
+
+// writeResultsDB upserts solution stats to SQLite database creating its schema if needed.
+// Solutions w/o UUID in their file names are keyed by names, absent stats are stored as NULL.
+func writeResultsDB(cfg *config, path string, sstats []*solutionStats, runTime time.Time) (err error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err = db.Exec(dbSchema); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	stmt, err := tx.Prepare(dbUpsert)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	ts := runTime.UTC().Format(time.RFC3339)
+	for _, st := range sstats {
+		name, uuid, _ := parseSolutionFileName(st.file)
+		if uuid == "" {
+			uuid = name
+		}
+		for bn, bst := range st.bstats {
+			if bst == nil {
+				continue
+			}
+			if _, err = stmt.Exec(cfg.exercise, uuid, bn, ts, st.goVersion, st.name, st.size, bst.time,
+				nullFloat(bst.throughput), nullInt(bst.mem), nullInt(bst.allocs)); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// nullFloat converts -1 sentinel of absent stats to NULL.
+func nullFloat(v float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: v, Valid: v != -1}
+}
+
+// nullInt converts -1 sentinel of absent stats to NULL.
+func nullInt(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v != -1}
+}
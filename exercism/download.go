@@ -0,0 +1,348 @@
+package exercism
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAddr is an address of exercism site.
+const DefaultAddr = "https://exercism.io"
+
+var (
+	// SolutionPathRE matches a solution path with UUID submatch.
+	SolutionPathRE = regexp.MustCompile("solutions/(([[:xdigit:]][[:xdigit:]]){16})")
+	// SolutionGroupsNumberRE matches a link to the last solutions group page with its number submatch.
+	SolutionGroupsNumberRE = regexp.MustCompile(`solutions\?page=([[:digit:]]+)">Last`)
+)
+
+// ErrNoSolutionGroups is an error of a solutions page w/o a number of solution groups.
+var ErrNoSolutionGroups = errors.New("can't find solution groups number")
+
+// SolutionGroupsNumber extracts a number of solution groups pages from the first one.
+// A missing number is reported with *PatternError.
+func SolutionGroupsNumber(page string) (uint64, error) {
+	ms := SolutionGroupsNumberRE.FindStringSubmatch(page)
+	if ms == nil {
+		return 0, regexpError(ErrNoSolutionGroups, "solution groups number", page, SolutionGroupsNumberRE)
+	}
+	return strconv.ParseUint(ms[1], 10, 64)
+}
+
+// Source is a source of solutions of an exercise and their test suite.
+// Errors of sources describe where they have happened.
+type Source interface {
+	// ListUUIDs returns UUIDs of all solutions.
+	ListUUIDs(ctx context.Context) (uuids []string, err error)
+	// FetchSolution returns code of a solution and its author name.
+	FetchSolution(ctx context.Context, uuid string) (code, author string, err error)
+	// FetchTestSuite returns test suite files by their names.
+	FetchTestSuite(ctx context.Context) (suite map[string]string, err error)
+}
+
+// SiteSource gets solutions of an exercise from site pages one by one.
+type SiteSource struct {
+	Addr     string       // site address, DefaultAddr by default
+	Track    string       // track language, go by default
+	Exercise string       // exercise slug
+	Client   *http.Client // HTTP client, a client with 5s timeout by default
+
+	// Fetch downloads a page, e.g. with retries or from a cache; a single GET request of Client by default.
+	Fetch func(ctx context.Context, urlv string) (page string, err error)
+}
+
+// get downloads a page of a solution with a given UUID or a solutions group page if it's empty.
+func (s *SiteSource) get(ctx context.Context, uuid string, page uint64) (content, urlv string, err error) {
+	addr, track := s.Addr, s.Track
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	if track == "" {
+		track = "go"
+	}
+	urlv = SolutionsURL(addr, track, s.Exercise, uuid)
+	if page != 0 {
+		urlv += "?page=" + strconv.FormatUint(page, 10)
+	}
+	if s.Fetch != nil {
+		content, err = s.Fetch(ctx, urlv)
+		return content, urlv, err
+	}
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	content, err = fetch(ctx, client, urlv)
+	return content, urlv, err
+}
+
+// GroupPage returns UUIDs of solutions on a group page with a given number starting from 1
+// and a total number of group pages, which is known from the first page only.
+// Errors of the download aren't wrapped, urlv is a URL of the page.
+func (s *SiteSource) GroupPage(ctx context.Context, n uint64) (uuids []string, total uint64, urlv string, err error) {
+	page, urlv, err := s.get(ctx, "", n)
+	if err != nil {
+		return nil, 0, urlv, err
+	}
+	// only the first page is sure to link the last one
+	if n == 1 {
+		if total, err = SolutionGroupsNumber(page); err != nil {
+			return nil, 0, urlv, err
+		}
+	}
+	for _, ms := range SolutionPathRE.FindAllStringSubmatch(page, -1) {
+		uuids = append(uuids, ms[1])
+	}
+	return uuids, total, urlv, nil
+}
+
+// ListUUIDs returns UUIDs of solutions of all group pages in order of appearance.
+func (s *SiteSource) ListUUIDs(ctx context.Context) (uuids []string, err error) {
+	seen := make(map[string]struct{})
+	for i, total := uint64(1), uint64(1); i <= total; i++ {
+		// the first page has total of pages
+		found, n, urlv, err := s.GroupPage(ctx, i)
+		if err != nil {
+			return nil, fmt.Errorf("download of %s failed: %w", urlv, err)
+		}
+		if i == 1 {
+			total = n
+		}
+		for _, uuid := range found {
+			if _, ok := seen[uuid]; !ok {
+				seen[uuid] = struct{}{}
+				uuids = append(uuids, uuid)
+			}
+		}
+	}
+	return uuids, nil
+}
+
+// FetchSolution returns code and author of a solution from its page.
+func (s *SiteSource) FetchSolution(ctx context.Context, uuid string) (code, author string, err error) {
+	page, urlv, err := s.get(ctx, uuid, 0)
+	if err != nil {
+		return "", "", fmt.Errorf("download of %s failed: %w", urlv, err)
+	}
+	return ExtractSolutionCode(page)
+}
+
+// FetchTestSuite returns test suite from a page of the first solution of the first group page.
+func (s *SiteSource) FetchTestSuite(ctx context.Context) (suite map[string]string, err error) {
+	page, urlv, err := s.get(ctx, "", 1)
+	if err != nil {
+		return nil, fmt.Errorf("download of %s failed: %w", urlv, err)
+	}
+	ms := SolutionPathRE.FindStringSubmatch(page)
+	if ms == nil {
+		return nil, regexpError(ErrNoTestSuite, "solution path", page, SolutionPathRE)
+	}
+	if page, urlv, err = s.get(ctx, ms[1], 0); err != nil {
+		return nil, fmt.Errorf("download of %s failed: %w", urlv, err)
+	}
+	return ExtractTestSuite(page)
+}
+
+// VerifyError is reported for a solution which stored code has failed verification after all retries.
+// The stored file is removed.
+type VerifyError struct {
+	Path string // path of the removed file
+	Err  error  // verification error
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("verification of %s failed: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the verification error.
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// DownloadOptions configure downloads of solutions.
+// Only Exercise is required, hooks customize where and how solutions are got and stored.
+type DownloadOptions struct {
+	Addr     string       // site address, DefaultAddr by default
+	Track    string       // track language, go by default
+	Exercise string       // exercise slug
+	Dir      string       // dir to store solutions and test-suite dir to
+	Client   *http.Client // HTTP client, a client with 5s timeout by default
+	Source   Source       // source of solutions, SiteSource of the fields above by default
+
+	// Go runs a download task, e.g. by a pool of workers; tasks are run one by one by default.
+	Go func(task func())
+	// KeepSuite skips download of test suite, e.g. if it has been downloaded before.
+	KeepSuite bool
+	// SaveSuite stores test suite files, they are written to test-suite dir by default.
+	SaveSuite func(suite map[string]string) error
+	// SaveSolution stores solution code and returns a path of its file, <uuid>-<author>.go file in dir by default.
+	SaveSolution func(uuid, author, code string) (path string, err error)
+	// Verify checks a stored solution file, e.g. to catch truncated downloads; it's fetched again up to Retries times
+	// if it fails. No files are verified by default.
+	Verify  func(path string) error
+	Retries int
+	// Logf logs details of downloads like retries, nothing is logged by default.
+	Logf func(format string, v ...interface{})
+	// Listed is called with UUIDs of all solutions before their downloads.
+	Listed func(uuids []string)
+	// Done is called after a solution download with its error if it has failed.
+	Done func(uuid, author string, err error)
+}
+
+// SolutionsURL returns a URL of a solution with a given UUID or of solutions list if it's empty.
+func SolutionsURL(addr, track, exercise, uuid string) string {
+	return strings.Join([]string{addr, "tracks", track, "exercises", exercise, "solutions", uuid}, "/")
+}
+
+// Download downloads all solutions of an exercise from a source, by default all published ones are stored
+// as <uuid>-<author>.go files and its test suite to test-suite dir.
+// n is a number of downloaded solutions, ones failed to be downloaded are skipped and reported to Done.
+func Download(ctx context.Context, opts DownloadOptions) (n int, err error) {
+	if opts.Source == nil {
+		if opts.Exercise == "" {
+			return 0, errors.New("no exercise")
+		}
+		opts.Source = &SiteSource{Addr: opts.Addr, Track: opts.Track, Exercise: opts.Exercise, Client: opts.Client}
+	}
+	if opts.Go == nil {
+		opts.Go = func(task func()) { task() }
+	}
+	if opts.SaveSuite == nil {
+		opts.SaveSuite = func(suite map[string]string) error { return saveSuite(opts.Dir, suite) }
+	}
+	if opts.SaveSolution == nil {
+		opts.SaveSolution = func(uuid, author, code string) (string, error) {
+			path := filepath.Join(opts.Dir, uuid+"-"+author+".go")
+			return path, ioutil.WriteFile(path, []byte(code), 0600)
+		}
+	}
+	if opts.Logf == nil {
+		opts.Logf = func(string, ...interface{}) {}
+	}
+	if opts.Done == nil {
+		opts.Done = func(string, string, error) {}
+	}
+
+	// get solution UUIDs
+	uuids, err := opts.Source.ListUUIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if opts.Listed != nil {
+		opts.Listed(uuids)
+	}
+
+	// get test suite unless it's kept
+	if err = os.MkdirAll(opts.Dir, 0700); err != nil {
+		return 0, err
+	}
+	if !opts.KeepSuite {
+		suite, err := opts.Source.FetchTestSuite(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if err = opts.SaveSuite(suite); err != nil {
+			return 0, err
+		}
+	}
+
+	// schedule downloads and stores
+	wg := sync.WaitGroup{}
+	mx := sync.Mutex{}
+	for _, id := range uuids {
+		uuid := id
+		wg.Add(1)
+
+		opts.Go(func() {
+			defer wg.Done()
+			if ctx.Err() != nil {
+				return
+			}
+			author, err := downloadSolution(ctx, &opts, uuid)
+			if err == nil {
+				mx.Lock()
+				n++
+				mx.Unlock()
+			}
+			opts.Done(uuid, author, err)
+		})
+	}
+
+	// wait all tasks
+	wg.Wait()
+
+	return n, ctx.Err()
+}
+
+// downloadSolution fetches, stores and verifies code of a solution.
+func downloadSolution(ctx context.Context, opts *DownloadOptions, uuid string) (author string, err error) {
+	for attempt := 0; ; attempt++ {
+		var code, path string
+		if code, author, err = opts.Source.FetchSolution(ctx, uuid); err != nil {
+			return "", err
+		}
+		path, err = opts.SaveSolution(uuid, author, code)
+		if err != nil {
+			return author, err
+		}
+		if opts.Verify == nil {
+			return author, nil
+		}
+
+		// truncated downloads are fetched again
+		if err = opts.Verify(path); err != nil {
+			if attempt < opts.Retries {
+				opts.Logf("verification of %s failed: %v; retry %d / %d", path, err, attempt+1, opts.Retries)
+				continue
+			}
+			os.Remove(path)
+			return author, &VerifyError{Path: path, Err: err}
+		}
+		return author, nil
+	}
+}
+
+// saveSuite writes test suite files to test-suite dir of a given dir.
+func saveSuite(dir string, suite map[string]string) error {
+	tsDir := filepath.Join(dir, "test-suite")
+	if err := os.MkdirAll(tsDir, 0700); err != nil {
+		return err
+	}
+	for fn, fc := range suite {
+		if err := ioutil.WriteFile(filepath.Join(tsDir, fn), []byte(fc), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetch downloads a page.
+func fetch(ctx context.Context, client *http.Client, urlv string) (string, error) {
+	req, err := http.NewRequest("GET", urlv, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status code %q", resp.Status)
+	}
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
@@ -0,0 +1,161 @@
+package exercism
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// blockingSource lists solutions and blocks their fetches until ctx is done.
+type blockingSource struct {
+	uuids []string
+}
+
+func (s *blockingSource) ListUUIDs(ctx context.Context) ([]string, error) {
+	return s.uuids, nil
+}
+
+func (s *blockingSource) FetchSolution(ctx context.Context, uuid string) (code, author string, err error) {
+	<-ctx.Done()
+	return "", "", ctx.Err()
+}
+
+func (s *blockingSource) FetchTestSuite(ctx context.Context) (map[string]string, error) {
+	return map[string]string{"ex_test.go": "package ex\n"}, nil
+}
+
+func TestDownloadCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	// the second solution isn't fetched after cancel of the first one
+	fetched := 0
+	start := time.Now()
+	n, err := Download(ctx, DownloadOptions{
+		Dir:    t.TempDir(),
+		Source: &blockingSource{uuids: []string{"a", "b"}},
+		Done: func(uuid, author string, err error) {
+			fetched++
+		},
+	})
+	if n != 0 || err != context.Canceled {
+		t.Errorf("downloaded and error = %d and %v, want 0 and %v", n, err, context.Canceled)
+	}
+	if fetched != 1 {
+		t.Errorf("fetched solutions = %d, want 1", fetched)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("return took %v", d)
+	}
+}
+
+func TestDownloadSite(t *testing.T) {
+	const uuid1, uuid2 = "0123456789abcdef0123456789abcdef", "fedcba9876543210fedcba9876543210"
+	solution := func(author string) string {
+		return "<img alt=\"Avatar of " + author + "\">" +
+			"<pre class='line-numbers solution-code'><code class='language-go'>package ex\n</code></pre>" +
+			"<div class='pane pane-2 test-suite'><h3>ex_test.go</h3><code class='language-go'>package ex\n</code></div>"
+	}
+	pages := map[string]string{
+		"/tracks/go/exercises/ex/solutions/?page=1":  "solutions/" + uuid1 + " <a href=\"solutions?page=2\">Last</a>",
+		"/tracks/go/exercises/ex/solutions/?page=2":  "solutions/" + uuid2 + " solutions/" + uuid1,
+		"/tracks/go/exercises/ex/solutions/" + uuid1: solution("alice"),
+		"/tracks/go/exercises/ex/solutions/" + uuid2: solution("bob"),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, page) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	n, err := Download(context.Background(), DownloadOptions{Addr: srv.URL, Exercise: "ex", Dir: dir})
+	if err != nil || n != 2 {
+		t.Fatalf("downloaded and error = %d and %v, want 2 and no error", n, err)
+	}
+	for _, fn := range []string{uuid1 + "-alice.go", uuid2 + "-bob.go", filepath.Join("test-suite", "ex_test.go")} {
+		if bs, err := ioutil.ReadFile(filepath.Join(dir, fn)); err != nil || string(bs) != "package ex\n" {
+			t.Errorf("%s = %q, %v", fn, bs, err)
+		}
+	}
+}
+
+func TestSiteSourceListUUIDs(t *testing.T) {
+	const uuid1, uuid2 = "0123456789abcdef0123456789abcdef", "fedcba9876543210fedcba9876543210"
+	pages := map[string]string{
+		"a/tracks/go/exercises/ex/solutions/?page=1": "solutions/" + uuid1 + " <a href=\"solutions?page=2\">Last</a>",
+		"a/tracks/go/exercises/ex/solutions/?page=2": "solutions/" + uuid2 + " solutions/" + uuid1,
+	}
+	fetched := make(map[string]int)
+	src := &SiteSource{Addr: "a", Exercise: "ex", Fetch: func(_ context.Context, urlv string) (string, error) {
+		fetched[urlv]++
+		page, ok := pages[urlv]
+		if !ok {
+			return "", errors.New("not found")
+		}
+		return page, nil
+	}}
+
+	uuids, err := src.ListUUIDs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uuids) != 2 || uuids[0] != uuid1 || uuids[1] != uuid2 {
+		t.Errorf("UUIDs = %v, want [%s %s]", uuids, uuid1, uuid2)
+	}
+	// each page is fetched once by a given fetcher
+	for urlv := range pages {
+		if fetched[urlv] != 1 {
+			t.Errorf("%s fetched %d times, want 1", urlv, fetched[urlv])
+		}
+	}
+	if len(fetched) != len(pages) {
+		t.Errorf("fetched pages = %v, want only group pages", fetched)
+	}
+}
+
+func TestPageREs(t *testing.T) {
+	const uuid = "0123456789abcdef0123456789ABCDEF"
+	cases := []struct {
+		name   string
+		re     *regexp.Regexp
+		in     string
+		groups map[int]string // expected submatches by index, nil if no match is expected
+	}{
+		{"solution path", SolutionPathRE, `<a href="/tracks/go/exercises/ex/solutions/` + uuid + `">`, map[int]string{1: uuid}},
+		{"short solution path", SolutionPathRE, `<a href="/tracks/go/exercises/ex/solutions/0123">`, nil},
+		{"groups number", SolutionGroupsNumberRE, `<a href="/tracks/go/exercises/ex/solutions?page=12">Last &raquo;</a>`, map[int]string{1: "12"}},
+		{"next group", SolutionGroupsNumberRE, `<a href="/tracks/go/exercises/ex/solutions?page=2">Next</a>`, nil},
+		{"author", authorRE, `<img alt="Avatar of some_one-2" src="/a.png">`, map[int]string{1: "some_one-2"}},
+	}
+	for _, c := range cases {
+		ms := c.re.FindStringSubmatch(c.in)
+		if c.groups == nil {
+			if ms != nil {
+				t.Errorf("%s: unexpected match %q", c.name, ms)
+			}
+			continue
+		}
+		if ms == nil {
+			t.Errorf("%s: no match of %q", c.name, c.in)
+			continue
+		}
+		for i, want := range c.groups {
+			if ms[i] != want {
+				t.Errorf("%s: group %d = %q, want %q", c.name, i, ms[i], want)
+			}
+		}
+	}
+}
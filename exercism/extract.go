@@ -0,0 +1,227 @@
+package exercism
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"html"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	testSuiteStartPattern    = "<div class='pane pane-2 test-suite'>"
+	testSuiteEndPattern      = "</div>"
+	codeStartPattern         = "<code class='language-go'>"
+	codeEndPattern           = "</code>"
+	solutionCodeStartPattern = "<pre class='line-numbers solution-code'>" + codeStartPattern
+	solutionCodeEndPattern   = codeEndPattern + "</pre>"
+	testFileNameStartPattern = "<h3>"
+	testFileNameEndPattern   = "</h3>"
+)
+
+var (
+	authorRE = regexp.MustCompile("Avatar of (([[:word:]]|-)+)")
+)
+
+// Errors of solution page content extraction.
+var (
+	ErrNoSolutionCode = errors.New("no solution code")
+	ErrNoAuthorName   = errors.New("no author name")
+	ErrNoTestSuite    = errors.New("no test suite")
+)
+
+// snippetLen is a max length of a page snippet in pattern errors.
+const snippetLen = 80
+
+// PatternError is returned when a pattern doesn't match a page, e.g. after its markup has changed.
+type PatternError struct {
+	Err     error  // one of ErrNo* errors
+	Pattern string // name of the pattern
+	Snippet string // page fragment where a match was expected
+}
+
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("%v: %s pattern doesn't match near %q", e.Err, e.Pattern, e.Snippet)
+}
+
+// Unwrap returns the underlying ErrNo* error.
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
+
+// matchError returns a pattern error for a failed FirstMatch of start and end patterns in a page.
+// The snippet follows the start pattern if only the end one is absent.
+func matchError(err error, name, page, sp, ep string) *PatternError {
+	if i := strings.Index(page, sp); i != -1 && sp != "" {
+		return &PatternError{Err: err, Pattern: name + " end", Snippet: snippet(page, i+len(sp))}
+	}
+	return &PatternError{Err: err, Pattern: name + " start", Snippet: snippet(page, nearest(page, sp))}
+}
+
+// regexpError returns a pattern error for a regexp that doesn't match a page.
+func regexpError(err error, name, page string, re *regexp.Regexp) *PatternError {
+	lit, _ := re.LiteralPrefix()
+	return &PatternError{Err: err, Pattern: name, Snippet: snippet(page, nearest(page, lit))}
+}
+
+// nearest returns an index of the longest prefix of pattern found in a page,
+// which is likely a place where the markup has changed, or 0 if nothing is found.
+func nearest(page, pattern string) int {
+	for n := len(pattern); n > 0; n-- {
+		if i := strings.Index(page, pattern[:n]); i != -1 {
+			return i
+		}
+	}
+	return 0
+}
+
+// snippet returns a short page fragment starting at i.
+func snippet(page string, i int) string {
+	if i+snippetLen < len(page) {
+		return page[i:i+snippetLen] + "..."
+	}
+	return page[i:]
+}
+
+// ExtractSolutionCode extracts solution code and its author name from a solution page.
+// Line endings of the code are kept as is.
+// Missing parts are reported with *PatternError.
+func ExtractSolutionCode(solutionPage string) (code, author string, err error) {
+	// extract author name
+	ms := authorRE.FindStringSubmatch(solutionPage)
+	if ms == nil {
+		return "", "", regexpError(ErrNoAuthorName, "author", solutionPage, authorRE)
+	}
+	author = html.UnescapeString(ms[1])
+
+	// extract code
+	m, _ := FirstMatch(solutionPage, solutionCodeStartPattern, solutionCodeEndPattern)
+	if m == "" {
+		return "", "", matchError(ErrNoSolutionCode, "solution code", solutionPage, solutionCodeStartPattern, solutionCodeEndPattern)
+	}
+	code = html.UnescapeString(m)
+
+	return code, author, nil
+}
+
+// ExtractTestSuite extracts test suite files by their names from a solution page.
+// Line endings of the files are kept as is.
+// Missing parts are reported with *PatternError.
+func ExtractTestSuite(solutionPage string) (suite map[string]string, err error) {
+	// locate test suite
+	ts, _ := FirstMatch(solutionPage, testSuiteStartPattern, testSuiteEndPattern)
+	if ts == "" {
+		return nil, matchError(ErrNoTestSuite, "test suite", solutionPage, testSuiteStartPattern, testSuiteEndPattern)
+	}
+
+	// extract test files
+	suite = make(map[string]string)
+	for {
+		// each file consumes the rest of suite, so it must shrink to avoid endless loop
+		prev := len(ts)
+
+		// locate file name
+		var m string
+		rest := ts
+		m, ts = FirstMatch(rest, testFileNameStartPattern, testFileNameEndPattern)
+		if m == "" {
+			if len(suite) == 0 {
+				return nil, matchError(ErrNoTestSuite, "test file name", rest, testFileNameStartPattern, testFileNameEndPattern)
+			}
+			break
+		}
+		name := html.UnescapeString(m)
+		// names come from untrusted pages and are used as file names
+		if name != filepath.Base(name) || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return nil, fmt.Errorf("invalid test file name %q", name)
+		}
+
+		// locate code
+		rest = ts
+		m, ts = FirstMatch(rest, codeStartPattern, codeEndPattern)
+		if m == "" {
+			return nil, matchError(ErrNoTestSuite, "test file code", rest, codeStartPattern, codeEndPattern)
+		}
+
+		// fill in suite
+		suite[name] = html.UnescapeString(m)
+
+		if len(ts) >= prev {
+			return nil, errors.New("test suite extraction stalled")
+		}
+	}
+
+	return suite, nil
+}
+
+// dataExts are extensions of data files referenced by test suites.
+var dataExts = map[string]bool{
+	".txt": true, ".json": true, ".csv": true, ".tsv": true, ".xml": true, ".yaml": true, ".yml": true,
+	".dat": true, ".in": true, ".out": true, ".bin": true, ".gz": true,
+}
+
+// DataFileNames returns sorted relative paths of data files referenced by string literals in Go files of a test suite,
+// e.g. testdata/input.txt. Only files of the suite dir and its testdata dir are returned since only they're copied
+// to builds, files present in the suite are skipped.
+func DataFileNames(suite map[string]string) (names []string) {
+	seen := make(map[string]bool)
+	fs := token.NewFileSet()
+	for fn, fc := range suite {
+		if filepath.Ext(fn) != ".go" {
+			continue
+		}
+		f, err := parser.ParseFile(fs, fn, fc, 0)
+		if err != nil {
+			continue
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			lit, ok := n.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			v, err := strconv.Unquote(lit.Value)
+			if err != nil || !dataExts[strings.ToLower(path.Ext(v))] {
+				return true
+			}
+			v = path.Clean(v)
+			if strings.Contains(v, "/") && !strings.HasPrefix(v, "testdata/") || strings.ContainsAny(v, `\:`) {
+				return true
+			}
+			if _, ok := suite[v]; !ok && !seen[v] {
+				seen[v] = true
+				names = append(names, v)
+			}
+			return true
+		})
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FirstMatch looks for a substring with given non-empty start and end patterns.
+// match contains the substring excluding patterns or empty string if nothing has been found.
+// out gets the remaining input string after the chunk and the end pattern.
+func FirstMatch(in, sp, ep string) (match, out string) {
+	if sp == "" || ep == "" {
+		return
+	}
+	sind := strings.Index(in, sp)
+	if sind == -1 {
+		return
+	}
+	sind += len(sp)
+
+	eind := strings.Index(in[sind:], ep)
+	if eind == -1 {
+		return
+	}
+
+	return in[sind : sind+eind], in[sind+eind+len(ep):]
+}
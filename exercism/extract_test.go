@@ -0,0 +1,139 @@
+package exercism
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPage is a minimal solution page with all parts extracted by the package.
+const testPage = `<html><body>
+<img alt="Avatar of alice" src="/avatars/alice.png">
+<pre class='line-numbers solution-code'><code class='language-go'>package ex
+
+func Sum(a, b int) int { return a + b }
+</code></pre>
+<div class='pane pane-2 test-suite'><h3>ex_test.go</h3><code class='language-go'>package ex
+
+import &quot;testing&quot;
+
+func TestSum(t *testing.T) {}
+</code><h3>cases_test.go</h3><code class='language-go'>package ex
+</code></div>
+</body></html>`
+
+func FuzzExtractSolutionCode(f *testing.F) {
+	f.Add(testPage)
+	f.Add("Avatar of bob" + solutionCodeStartPattern + solutionCodeEndPattern)
+	f.Add("Avatar of " + solutionCodeStartPattern + "&amp;" + solutionCodeEndPattern)
+	f.Fuzz(func(t *testing.T, page string) {
+		_, author, err := ExtractSolutionCode(page)
+		if err != nil {
+			return
+		}
+		if author == "" {
+			t.Errorf("empty author of a page without errors")
+		}
+	})
+}
+
+func FuzzExtractTestSuite(f *testing.F) {
+	f.Add(testPage)
+	f.Add(testSuiteStartPattern + "<h3>../x_test.go</h3>" + codeStartPattern + codeEndPattern + testSuiteEndPattern)
+	f.Add(testSuiteStartPattern + "<h3></h3><h3>" + codeStartPattern + codeEndPattern + testSuiteEndPattern)
+	f.Fuzz(func(t *testing.T, page string) {
+		suite, err := ExtractTestSuite(page)
+		if err != nil {
+			return
+		}
+		if len(suite) == 0 {
+			t.Errorf("empty suite of a page without errors")
+		}
+		// names are used as file names inside of a suite dir
+		for name := range suite {
+			if name != filepath.Base(name) || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+				t.Errorf("unsafe file name %q", name)
+			}
+		}
+		DataFileNames(suite)
+	})
+}
+
+func TestExtractTestSuite(t *testing.T) {
+	suite, err := ExtractTestSuite(testPage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suite) != 2 || suite["cases_test.go"] != "package ex\n" || !strings.Contains(suite["ex_test.go"], `import "testing"`) {
+		t.Errorf("unexpected suite %q", suite)
+	}
+}
+
+func TestExtractTestSuitePathological(t *testing.T) {
+	const n = 100000
+	names := strings.Repeat("<h3>x_test.go</h3>", n)
+
+	cases := []struct {
+		name string
+		page string
+		err  bool
+	}{
+		{"names w/o code", testSuiteStartPattern + names + testSuiteEndPattern, true},
+		{"empty names w/o code", testSuiteStartPattern + strings.Repeat("<h3></h3>", n) + testSuiteEndPattern, true},
+		{"unclosed names", testSuiteStartPattern + strings.Repeat("<h3>", n) + testSuiteEndPattern, true},
+		{"names before code", testSuiteStartPattern + names + codeStartPattern + "package x" + codeEndPattern + testSuiteEndPattern, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				suite, err := ExtractTestSuite(c.page)
+				if c.err {
+					if err == nil {
+						t.Errorf("no error, suite of %d files", len(suite))
+					}
+					return
+				}
+				if err != nil || len(suite) != 1 || suite["x_test.go"] != "package x" {
+					t.Errorf("got %d files, %v", len(suite), err)
+				}
+			}()
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("extraction hasn't finished")
+			}
+		})
+	}
+}
+
+func TestDataFileNames(t *testing.T) {
+	suite := map[string]string{
+		"ex_test.go": `package ex
+
+var files = []string{
+	"input.txt",
+	"./testdata/cases.json",
+	"testdata/nested/big.CSV",
+	"testdata/../top.dat",
+	"data/other.txt",
+	"../up.txt",
+	"testdata/../../up.txt",
+	"/abs/file.txt",
+	"c:file.txt",
+	"present.json",
+	"input.txt",
+	"code.go",
+}
+`,
+		"present.json": "{}",
+		"notes.md":     `"ignored.txt"`,
+	}
+	want := []string{"input.txt", "testdata/cases.json", "testdata/nested/big.CSV", "top.dat"}
+	if names := DataFileNames(suite); !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %q, want %q", names, want)
+	}
+}
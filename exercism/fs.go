@@ -0,0 +1,146 @@
+package exercism
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ListSolutionFiles returns paths of all solution files relative to a solutions dir.
+// Both layouts are supported: Go files in the dir and <name>/<name>.go files of nested dirs.
+// Test suite and other nested dirs are ignored.
+func ListSolutionFiles(dir string) (names []string, err error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range fis {
+		switch {
+		case fi.IsDir() && fi.Name() != "test-suite":
+			fn := filepath.Join(fi.Name(), fi.Name()+".go")
+			if sfi, err := os.Stat(filepath.Join(dir, fn)); err == nil && regular(sfi) {
+				names = append(names, fn)
+			}
+		case regular(fi) && filepath.Ext(fi.Name()) == ".go":
+			names = append(names, fi.Name())
+		}
+	}
+	return names, nil
+}
+
+// MakeBuildDir creates a temp dir with a solution, test suite files and include files.
+// A solution is a file or a dir of nested layout, which is copied as a whole.
+// The caller removes the dir.
+func MakeBuildDir(solution, suiteDir string, include ...string) (dir string, err error) {
+	tmp, err := ioutil.TempDir("", "")
+	if err != nil {
+		return "", fmt.Errorf("temp dir create error: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(tmp)
+		}
+	}()
+
+	// copy all required files to temp dir
+	fi, err := os.Stat(solution)
+	if err != nil {
+		return "", fmt.Errorf("copy file error: %v", err)
+	}
+	if fi.IsDir() {
+		err = copyFiles(solution, tmp)
+	} else {
+		err = copyFile(solution, filepath.Join(tmp, filepath.Base(solution)))
+	}
+	if err != nil {
+		return "", fmt.Errorf("copy file error: %v", err)
+	}
+	if err = copyFiles(suiteDir, tmp); err != nil {
+		return "", fmt.Errorf("copy test suite files error: %v", err)
+	}
+	for _, ip := range include {
+		ipath := filepath.Join(tmp, filepath.Base(ip))
+		if _, err = os.Stat(ipath); err == nil {
+			return "", fmt.Errorf("include file %s collides with existing file", ip)
+		}
+		if err = copyFile(ip, ipath); err != nil {
+			return "", fmt.Errorf("copy include file error: %v", err)
+		}
+	}
+	return tmp, nil
+}
+
+// copyFile copies only a regular file.
+func copyFile(srcPath, destPath string) error {
+	// check file type
+	fi, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	if !regular(fi) {
+		return errors.New("not a regular file")
+	}
+
+	// copy data
+	bs, err := ioutil.ReadFile(srcPath)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(destPath, bs, fi.Mode())
+}
+
+// copyFiles copies all files from srcDir to destDir.
+// Nested dirs are ignored except testdata one of data files, which is copied recursively.
+func copyFiles(srcDir, destDir string) error {
+	fis, err := ioutil.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range fis {
+		if fi.IsDir() {
+			if fi.Name() == "testdata" {
+				if err = copyDir(filepath.Join(srcDir, fi.Name()), filepath.Join(destDir, fi.Name())); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		n := fi.Name()
+		if err = copyFile(filepath.Join(srcDir, n), filepath.Join(destDir, n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyDir copies all regular files of srcDir and its nested dirs to destDir.
+func copyDir(srcDir, destDir string) error {
+	if err := os.MkdirAll(destDir, 0700); err != nil {
+		return err
+	}
+	fis, err := ioutil.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range fis {
+		sp, dp := filepath.Join(srcDir, fi.Name()), filepath.Join(destDir, fi.Name())
+		switch {
+		case fi.IsDir():
+			err = copyDir(sp, dp)
+		case regular(fi):
+			err = copyFile(sp, dp)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func regular(fi os.FileInfo) bool {
+	return fi.Mode()&os.ModeType == 0
+}
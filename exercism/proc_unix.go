@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package exercism
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setKillGroup makes a command run in its own process group, which is killed as a whole on cancel,
+// so processes it starts, e.g. test binaries of go test, don't outlive it.
+func setKillGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,6 @@
+package exercism
+
+import "os/exec"
+
+// setKillGroup does nothing, only a command itself is killed on cancel.
+func setKillGroup(cmd *exec.Cmd) {}
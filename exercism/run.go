@@ -0,0 +1,320 @@
+package exercism
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BenchOptions configure runs of benchmarks.
+type BenchOptions struct {
+	GoBin       string        // go binary, go from PATH by default
+	Pattern     string        // benchmarks pattern, all by default
+	Mem         bool          // collect mem stats
+	Count       int           // number of runs of each benchmark, 1 by default
+	Benchtime   time.Duration // run time of each benchmark, go test default if 0
+	Tags        []string      // build tags
+	RunTests    bool          // run tests and examples by a separate go test before benchmarks
+	MaxProcs    int           // GOMAXPROCS of benchmarks, inherited if 0
+	Env         []string      // KEY=VAL variables overriding inherited ones
+	Reducer     string        // reducer of several runs, median by default
+	Parallelism int           // number of solutions benched simultaneously by Bench, 1 by default
+}
+
+// BenchOption sets a field of BenchOptions.
+type BenchOption func(o *BenchOptions)
+
+// WithGoBinary sets go binary.
+func WithGoBinary(bin string) BenchOption {
+	return func(o *BenchOptions) { o.GoBin = bin }
+}
+
+// WithPattern sets benchmarks pattern.
+func WithPattern(pattern string) BenchOption {
+	return func(o *BenchOptions) { o.Pattern = pattern }
+}
+
+// WithMem enables mem stats.
+func WithMem() BenchOption {
+	return func(o *BenchOptions) { o.Mem = true }
+}
+
+// WithCount sets number of runs of each benchmark.
+func WithCount(n int) BenchOption {
+	return func(o *BenchOptions) { o.Count = n }
+}
+
+// WithBenchtime sets run time of each benchmark.
+func WithBenchtime(d time.Duration) BenchOption {
+	return func(o *BenchOptions) { o.Benchtime = d }
+}
+
+// WithRunTests enables tests and examples run before benchmarks.
+func WithRunTests() BenchOption {
+	return func(o *BenchOptions) { o.RunTests = true }
+}
+
+// WithTags adds build tags.
+func WithTags(tags ...string) BenchOption {
+	return func(o *BenchOptions) { o.Tags = append(o.Tags, tags...) }
+}
+
+// WithMaxProcs sets GOMAXPROCS of benchmarks.
+func WithMaxProcs(n int) BenchOption {
+	return func(o *BenchOptions) { o.MaxProcs = n }
+}
+
+// WithEnv adds KEY=VAL environment variables.
+func WithEnv(env ...string) BenchOption {
+	return func(o *BenchOptions) { o.Env = append(o.Env, env...) }
+}
+
+// WithReducer sets reducer of several runs.
+func WithReducer(reducer string) BenchOption {
+	return func(o *BenchOptions) { o.Reducer = reducer }
+}
+
+// WithParallelism sets number of solutions benched simultaneously.
+func WithParallelism(n int) BenchOption {
+	return func(o *BenchOptions) { o.Parallelism = n }
+}
+
+// NewBenchOptions returns bench options with given ones applied.
+func NewBenchOptions(opts ...BenchOption) BenchOptions {
+	o := BenchOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (o *BenchOptions) goBin() string {
+	if o.GoBin == "" {
+		return "go"
+	}
+	return o.GoBin
+}
+
+func (o *BenchOptions) reducer() string {
+	if o.Reducer == "" {
+		return ReduceMedian
+	}
+	return o.Reducer
+}
+
+// PartialError is returned by RunBench with stats of benchmarks completed before go test has failed,
+// e.g. because of its timeout.
+type PartialError struct {
+	Err error // go test error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("partial results: %v", e.Err)
+}
+
+// Unwrap returns the go test error.
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// RunBench runs benchmarks (and tests if requested) in a given dir.
+// out contains raw combined output of go test even if it has failed.
+// If go test fails after some benchmarks have been printed, their stats are returned with *PartialError.
+// Tests are run by a separate go test before benchmarks since go test doesn't run benchmarks after failed tests,
+// so failed tests don't fail the run and can be found in out with FailedTests.
+func RunBench(ctx context.Context, dirPath string, opts BenchOptions) (bstats map[string]*BenchStats, out string, err error) {
+	pattern := opts.Pattern
+	if pattern == "" {
+		pattern = "."
+	}
+
+	// args and env common to tests and benchmarks
+	common := []string{}
+	if len(opts.Tags) > 0 {
+		common = append(common, "-tags", strings.Join(opts.Tags, ","))
+	}
+	// later values override earlier ones and inherited environment
+	env := []string{}
+	if opts.MaxProcs > 0 {
+		env = append(env, "GOMAXPROCS="+strconv.Itoa(opts.MaxProcs))
+	}
+	env = append(env, opts.Env...)
+
+	// run tests only if requested, failed ones aren't an error
+	if opts.RunTests {
+		args := append([]string{"test", "-run", ".", "-bench", "^$"}, common...)
+		out, err = RunCmd(ctx, opts.goBin(), dirPath, env, args...)
+		if err != nil && (ctx.Err() != nil || len(FailedTests(out)) == 0) {
+			return nil, out, err
+		}
+	}
+
+	// run benchmarks only
+	args := []string{"test", "-run", "^$", "-bench", pattern}
+	if opts.Mem {
+		args = append(args, "-benchmem")
+	}
+	if opts.Count > 1 {
+		args = append(args, "-count", strconv.Itoa(opts.Count))
+	}
+	if opts.Benchtime > 0 {
+		args = append(args, "-benchtime", opts.Benchtime.String())
+	}
+	args = append(args, common...)
+	bout, err := RunCmd(ctx, opts.goBin(), dirPath, env, args...)
+	out += bout
+	if err != nil {
+		// salvage benchmarks printed before the failure unless the run is canceled
+		if ctx.Err() != nil {
+			return nil, out, err
+		}
+		bstats, perr := ParseBenchOutput(bout, opts.reducer())
+		if perr != nil {
+			return nil, out, err
+		}
+		return bstats, out, &PartialError{Err: err}
+	}
+
+	// extract stats
+	bstats, err = ParseBenchOutput(bout, opts.reducer())
+	return bstats, out, err
+}
+
+// Bench runs benchmarks of a test suite in suiteDir for each solution in dir, see ListSolutionFiles for its layouts.
+// Solutions failed to be benchmarked are skipped.
+func Bench(ctx context.Context, dir, suiteDir string, opts ...BenchOption) (sstats []*SolutionStats, err error) {
+	o := NewBenchOptions(opts...)
+	fnames, err := ListSolutionFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	tc, err := GetToolchain(ctx, o.goBin())
+	if err != nil {
+		return nil, err
+	}
+
+	// bench up to parallelism solutions simultaneously keeping their order
+	n := o.Parallelism
+	if n < 1 {
+		n = 1
+	}
+	results := make([]*SolutionStats, len(fnames))
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, n)
+
+	for i, fn := range fnames {
+		if ctx.Err() != nil {
+			break
+		}
+		idx, fname := i, fn
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if st, err := benchSolution(ctx, dir, fname, suiteDir, o); err == nil {
+				st.GoVersion = tc.Version
+				results[idx] = st
+			}
+		}()
+	}
+	wg.Wait()
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, st := range results {
+		if st != nil {
+			sstats = append(sstats, st)
+		}
+	}
+	return sstats, nil
+}
+
+// benchSolution benchmarks a solution file with a path relative to dir in a build dir with test suite files.
+func benchSolution(ctx context.Context, dir, fname, suiteDir string, opts BenchOptions) (*SolutionStats, error) {
+	path := filepath.Join(dir, fname)
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	size, err := CodeSize(path, src, SizeSymbols, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// a nested solution dir is copied as a whole
+	solution := path
+	if d := filepath.Dir(fname); d != "." {
+		solution = filepath.Join(dir, d)
+	}
+	tmp, err := MakeBuildDir(solution, suiteDir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	bstats, _, err := RunBench(ctx, tmp, opts)
+	_, partial := err.(*PartialError)
+	if err != nil && !partial {
+		return nil, err
+	}
+	return &SolutionStats{
+		File:       fname,
+		Name:       strings.TrimSuffix(filepath.Base(fname), ".go"),
+		Size:       size,
+		Benchmarks: bstats,
+		Partial:    partial,
+	}, nil
+}
+
+// Toolchain is a go binary with its version.
+type Toolchain struct {
+	Bin      string
+	Version  string // e.g. go1.12.5
+	Platform string // e.g. linux/amd64
+}
+
+// GetToolchain returns a toolchain of a go binary.
+func GetToolchain(ctx context.Context, goBin string) (*Toolchain, error) {
+	out, err := RunCmd(ctx, goBin, "", nil, "version")
+	if err != nil {
+		return nil, fmt.Errorf("version of %s is unknown: %v", goBin, err)
+	}
+	// output format: go version <version> <os>/<arch>
+	fs := strings.Fields(out)
+	if len(fs) < 4 {
+		return nil, fmt.Errorf("version of %s is unknown: %q", goBin, out)
+	}
+	return &Toolchain{
+		Bin:      goBin,
+		Version:  fs[2],
+		Platform: fs[3],
+	}, nil
+}
+
+// RunCmd runs a command in a given dir with env added to the inherited environment.
+// The command is killed with processes it has started if ctx is done.
+func RunCmd(ctx context.Context, name, dir string, env []string, arg ...string) (out string, err error) {
+	cmd := exec.CommandContext(ctx, name, arg...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	setKillGroup(cmd)
+	// output pipes may be held by processes left running
+	cmd.WaitDelay = time.Second
+
+	bs, err := cmd.CombinedOutput()
+	return string(bs), err
+}
@@ -0,0 +1,306 @@
+package exercism
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPackage is a module with a solution, benchmarks and a test.
+// BenchmarkTagged is built with tagged tag only, BenchmarkEnv is skipped unless BENCH_ENV is set.
+var testPackage = map[string]string{
+	"go.mod": "module ex\n\ngo 1.16\n",
+	"ex.go":  "package ex\n\n// Sum sums.\nfunc Sum(a, b int) int {\n\treturn a + b\n}\n",
+	"ex_test.go": `package ex
+
+import (
+	"os"
+	"testing"
+)
+
+var sink []int
+
+func TestSum(t *testing.T) {
+	if Sum(1, 2) != 3 {
+		t.Fail()
+	}
+}
+
+func BenchmarkSmall(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Sum(i, 1)
+	}
+}
+
+func BenchmarkAlloc(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		sink = make([]int, 8)
+	}
+}
+
+func BenchmarkEnv(b *testing.B) {
+	if os.Getenv("BENCH_ENV") == "" {
+		b.Skip()
+	}
+	for i := 0; i < b.N; i++ {
+		Sum(i, 2)
+	}
+}
+`,
+	"tagged_test.go": "// +build tagged\n\npackage ex\n\nimport \"testing\"\n\nfunc BenchmarkTagged(b *testing.B) {\n\tfor i := 0; i < b.N; i++ {\n\t\tSum(i, 3)\n\t}\n}\n",
+}
+
+// writeTestPackage writes files of a package to a temp dir and returns it.
+func writeTestPackage(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for fn, fc := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, fn), []byte(fc), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// benchNames returns sorted names of benchmarks with stats.
+func benchNames(bstats map[string]*BenchStats) string {
+	names := []string{}
+	for bn := range bstats {
+		names = append(names, bn)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+func TestNewBenchOptions(t *testing.T) {
+	o := NewBenchOptions(
+		WithGoBinary("go1.12"),
+		WithPattern("Small"),
+		WithMem(),
+		WithCount(3),
+		WithBenchtime(time.Second),
+		WithRunTests(),
+		WithTags("a"),
+		WithTags("b", "c"),
+		WithMaxProcs(2),
+		WithEnv("A=1"),
+		WithEnv("B=2"),
+		WithReducer(ReduceMin),
+		WithParallelism(4),
+	)
+	want := BenchOptions{
+		GoBin:       "go1.12",
+		Pattern:     "Small",
+		Mem:         true,
+		Count:       3,
+		Benchtime:   time.Second,
+		Tags:        []string{"a", "b", "c"},
+		RunTests:    true,
+		MaxProcs:    2,
+		Env:         []string{"A=1", "B=2"},
+		Reducer:     ReduceMin,
+		Parallelism: 4,
+	}
+	if !reflect.DeepEqual(o, want) {
+		t.Errorf("options = %+v, want %+v", o, want)
+	}
+
+	// defaults are applied to zero options
+	o = NewBenchOptions()
+	if o.goBin() != "go" || o.reducer() != ReduceMedian {
+		t.Errorf("default go binary and reducer = %s and %s, want go and %s", o.goBin(), o.reducer(), ReduceMedian)
+	}
+}
+
+func TestRunBenchOptions(t *testing.T) {
+	dir := writeTestPackage(t, testPackage)
+	fast := WithBenchtime(time.Millisecond)
+
+	cases := []struct {
+		name  string
+		opts  []BenchOption
+		names string
+		check func(t *testing.T, bstats map[string]*BenchStats, out string)
+	}{
+		{"default", []BenchOption{fast}, "BenchmarkAlloc BenchmarkSmall", func(t *testing.T, bstats map[string]*BenchStats, out string) {
+			if st := bstats["BenchmarkAlloc"]; st.Mem != -1 || st.Allocs != -1 || len(st.Samples) != 1 {
+				t.Errorf("stats = %+v, want no mem stats and a single sample", *st)
+			}
+			if strings.Contains(out, "TestSum") {
+				t.Errorf("tests are run:\n%s", out)
+			}
+		}},
+		{"pattern and mem", []BenchOption{fast, WithPattern("Alloc"), WithMem()}, "BenchmarkAlloc", func(t *testing.T, bstats map[string]*BenchStats, out string) {
+			if st := bstats["BenchmarkAlloc"]; st.Mem <= 0 || st.Allocs != 1 {
+				t.Errorf("stats = %+v, want mem stats of 1 alloc", *st)
+			}
+		}},
+		{"count and min", []BenchOption{fast, WithPattern("Small"), WithCount(3), WithReducer(ReduceMin)}, "BenchmarkSmall", func(t *testing.T, bstats map[string]*BenchStats, out string) {
+			st := bstats["BenchmarkSmall"]
+			min := st.Samples[0]
+			for _, v := range st.Samples {
+				if v < min {
+					min = v
+				}
+			}
+			if len(st.Samples) != 3 || st.Time != min {
+				t.Errorf("stats = %+v, want min of 3 samples", *st)
+			}
+		}},
+		{"tags and env", []BenchOption{fast, WithTags("tagged"), WithEnv("BENCH_ENV=1")}, "BenchmarkAlloc BenchmarkEnv BenchmarkSmall BenchmarkTagged", nil},
+		{"tests and max procs", []BenchOption{fast, WithPattern("Small"), WithRunTests(), WithMaxProcs(2)}, "BenchmarkSmall", func(t *testing.T, bstats map[string]*BenchStats, out string) {
+			if !strings.Contains(out, "BenchmarkSmall-2") {
+				t.Errorf("benchmarks aren't run with GOMAXPROCS 2:\n%s", out)
+			}
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bstats, out, err := RunBench(context.Background(), dir, NewBenchOptions(c.opts...))
+			if err != nil {
+				t.Fatalf("%v\n%s", err, out)
+			}
+			if names := benchNames(bstats); names != c.names {
+				t.Fatalf("benchmarks = %s, want %s", names, c.names)
+			}
+			if c.check != nil {
+				c.check(t, bstats, out)
+			}
+		})
+	}
+}
+
+func TestBenchParallelism(t *testing.T) {
+	suite := writeTestPackage(t, map[string]string{
+		"go.mod":     testPackage["go.mod"],
+		"ex_test.go": testPackage["ex_test.go"],
+	})
+	dir := writeTestPackage(t, map[string]string{
+		"a.go":      "package ex\n\nfunc Sum(a, b int) int { return a + b }\n",
+		"b.go":      "package ex\n\nfunc Sum(a, b int) int { return b + a }\n",
+		"broken.go": "package ex\n\nfunc Sum(a, b int) int { return a - }\n",
+	})
+	if err := os.MkdirAll(filepath.Join(dir, "c"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "c", "c.go"), []byte("package ex\n\nfunc Sum(a, b int) int {\n\treturn a + b + 0\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{1, 3} {
+		sstats, err := Bench(context.Background(), dir, suite,
+			WithBenchtime(time.Millisecond), WithPattern("Small"), WithMem(), WithParallelism(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// broken solution is skipped, others are in order
+		files := []string{}
+		for _, st := range sstats {
+			files = append(files, st.File)
+			if st.GoVersion == "" || st.Size == 0 || st.Benchmarks["BenchmarkSmall"] == nil || st.Benchmarks["BenchmarkSmall"].Mem == -1 {
+				t.Errorf("parallelism %d: stats of %s = %+v", n, st.File, *st)
+			}
+		}
+		if want := []string{"a.go", "b.go", filepath.Join("c", "c.go")}; !reflect.DeepEqual(files, want) {
+			t.Errorf("parallelism %d: benched solutions = %v, want %v", n, files, want)
+		}
+	}
+}
+
+func TestRunBenchCancel(t *testing.T) {
+	dir := writeTestPackage(t, map[string]string{
+		"go.mod": testPackage["go.mod"],
+		"ex_test.go": "package ex\n\nimport (\n\t\"testing\"\n\t\"time\"\n)\n\n" +
+			"func BenchmarkSlow(b *testing.B) {\n\ttime.Sleep(time.Minute)\n}\n",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(time.Second, cancel)
+
+	start := time.Now()
+	bstats, _, err := RunBench(ctx, dir, NewBenchOptions())
+	if err == nil || bstats != nil {
+		t.Errorf("stats and error = %v and %v, want only error", bstats, err)
+	}
+	if _, partial := err.(*PartialError); partial {
+		t.Errorf("canceled run has partial results: %v", err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("return took %v", d)
+	}
+}
+
+func TestRunBenchPartial(t *testing.T) {
+	// go test output is truncated by a panic of BenchmarkB after BenchmarkA has completed
+	const benchmarks = "package ex\n\nimport \"testing\"\n\n" +
+		"func BenchmarkA(b *testing.B) {\n\tfor i := 0; i < b.N; i++ {\n\t}\n}\n\n" +
+		"func BenchmarkB(b *testing.B) {\n\tpanic(\"truncated\")\n}\n\n" +
+		"func BenchmarkC(b *testing.B) {\n\tfor i := 0; i < b.N; i++ {\n\t}\n}\n"
+	dir := writeTestPackage(t, map[string]string{
+		"go.mod":     testPackage["go.mod"],
+		"ex_test.go": benchmarks,
+	})
+	fast := WithBenchtime(time.Millisecond)
+
+	bstats, out, err := RunBench(context.Background(), dir, NewBenchOptions(fast))
+	var perr *PartialError
+	if !errors.As(err, &perr) || perr.Err == nil {
+		t.Fatalf("error = %v, want partial results", err)
+	}
+	if names := benchNames(bstats); names != "BenchmarkA" {
+		t.Errorf("benchmarks = %q, want BenchmarkA", names)
+	}
+	if !strings.Contains(out, "truncated") {
+		t.Errorf("output w/o panic: %s", out)
+	}
+
+	// no results are salvaged if go test fails before any benchmark
+	bstats, _, err = RunBench(context.Background(), dir, NewBenchOptions(fast, WithPattern("^Benchmark[BC]$")))
+	if err == nil || bstats != nil || errors.As(err, &perr) {
+		t.Errorf("stats and error = %v and %v, want only go test error", bstats, err)
+	}
+}
+
+func TestRunBenchFailedTests(t *testing.T) {
+	dir := writeTestPackage(t, map[string]string{
+		"go.mod": testPackage["go.mod"],
+		"ex_test.go": "package ex\n\nimport \"testing\"\n\n" +
+			"func TestFail(t *testing.T) {\n\tt.Fail()\n}\n\n" +
+			"func TestPass(t *testing.T) {}\n\n" +
+			"func BenchmarkA(b *testing.B) {\n\tfor i := 0; i < b.N; i++ {\n\t}\n}\n",
+	})
+	fast := WithBenchtime(time.Millisecond)
+
+	// benchmarks are run despite failed tests
+	bstats, out, err := RunBench(context.Background(), dir, NewBenchOptions(fast, WithRunTests()))
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if names := benchNames(bstats); names != "BenchmarkA" {
+		t.Errorf("benchmarks = %q, want BenchmarkA", names)
+	}
+	if failed := FailedTests(out); !reflect.DeepEqual(failed, []string{"TestFail"}) {
+		t.Errorf("failed tests = %v, want [TestFail]\n%s", failed, out)
+	}
+
+	// tests aren't run by default
+	_, out, err = RunBench(context.Background(), dir, NewBenchOptions(fast))
+	if err != nil || len(FailedTests(out)) != 0 {
+		t.Errorf("tests are run by default, error %v:\n%s", err, out)
+	}
+
+	// tests failed to build fail the run
+	if err := ioutil.WriteFile(filepath.Join(dir, "broken_test.go"), []byte("package ex\n\nfunc TestBroken(t *testing.T) {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	bstats, _, err = RunBench(context.Background(), dir, NewBenchOptions(fast, WithRunTests()))
+	if err == nil || bstats != nil {
+		t.Errorf("stats and error = %v and %v, want only build error", bstats, err)
+	}
+}
@@ -0,0 +1,119 @@
+package exercism
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"unicode"
+)
+
+// Code size metrics.
+const (
+	SizeSymbols = "symbols"
+	SizeTokens  = "tokens"
+)
+
+type codeRange struct {
+	start int
+	end   int
+}
+
+type codeRanges []*codeRange
+
+func (ranges *codeRanges) include(offset int) bool {
+	for _, r := range *ranges {
+		if offset >= r.start && offset < r.end {
+			return true
+		}
+	}
+	return false
+}
+
+func (ranges *codeRanges) add(start, end int) {
+	*ranges = append(*ranges, &codeRange{
+		start: start,
+		end:   end,
+	})
+}
+
+// CodeSize returns number of symbols in source code w/o white spaces and comments
+// or number of tokens w/o comments for tokens metric.
+// Source code is gofmt-ed first if normalize is set.
+// File name is used only for error messages.
+func CodeSize(fileName string, src []byte, metric string, normalize bool) (size uint, err error) {
+	if normalize {
+		if src, err = format.Source(src); err != nil {
+			return
+		}
+	}
+	if metric == SizeTokens {
+		return CountTokens(fileName, src)
+	}
+
+	// exclude comments and ignore white spaces in string and char literals
+	var (
+		exclude, ignore codeRanges
+	)
+
+	// parse source code
+	fs := token.NewFileSet()
+	f, err := parser.ParseFile(fs, fileName, src, parser.ParseComments)
+	if err != nil {
+		return
+	}
+
+	// find all comments, string and char literals
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.Comment:
+			exclude.add(fs.Position(v.Pos()).Offset, fs.Position(v.End()).Offset)
+		case *ast.BasicLit:
+			if v.Kind == token.STRING || v.Kind == token.CHAR {
+				ignore.add(fs.Position(v.Pos()).Offset, fs.Position(v.End()).Offset)
+			}
+		}
+		return true
+	})
+
+	// count only relevant code symbols
+	for i, r := range string(src) {
+		if exclude.include(i) {
+			continue
+		}
+		if ignore.include(i) || !unicode.IsSpace(r) {
+			size++
+		}
+	}
+	return size, nil
+}
+
+// CountTokens returns number of lexical tokens in source code w/o comments and automatically inserted semicolons.
+func CountTokens(fileName string, src []byte) (n uint, err error) {
+	fs := token.NewFileSet()
+	f := fs.AddFile(fileName, fs.Base(), len(src))
+
+	var s scanner.Scanner
+	s.Init(f, src, func(pos token.Position, msg string) {
+		if err == nil {
+			err = fmt.Errorf("%s: %s", pos, msg)
+		}
+	}, 0)
+
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		n++
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
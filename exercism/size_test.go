@@ -0,0 +1,69 @@
+package exercism
+
+import "testing"
+
+// unformattedCode has statements ended by semicolons, which gofmt drops.
+const unformattedCode = "package ex\n\n// Sum sums.\nfunc Sum(a, b int) int {\n  s := a;\n  s += b;\n  return s;\n}\n"
+
+const formattedCode = "package ex\n\n// Sum sums.\nfunc Sum(a, b int) int {\n\ts := a\n\ts += b\n\treturn s\n}\n"
+
+func TestCodeSizeNormalize(t *testing.T) {
+	cases := []struct {
+		name      string
+		src       string
+		raw, norm uint
+	}{
+		// semicolons are dropped by formatting
+		{"unformatted", unformattedCode, 47, 44},
+		{"formatted", formattedCode, 44, 44},
+		// white spaces in literals are kept as is
+		{"literal", "package ex\nconst s = \"a  b\"\n", 22, 22},
+	}
+	for _, c := range cases {
+		raw, err := CodeSize(c.name+".go", []byte(c.src), SizeSymbols, false)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		norm, err := CodeSize(c.name+".go", []byte(c.src), SizeSymbols, true)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if raw != c.raw || norm != c.norm {
+			t.Errorf("%s: raw and normalized sizes = %d and %d, want %d and %d", c.name, raw, norm, c.raw, c.norm)
+		}
+	}
+
+	if _, err := CodeSize("broken.go", []byte("package ex\nfunc {"), SizeSymbols, true); err == nil {
+		t.Error("no error of broken code in normalize mode")
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	cases := []struct {
+		src  string
+		want uint
+	}{
+		// package ex
+		{"package ex\n", 2},
+		// comments and implicit semicolons aren't counted
+		{"package ex // comment\n/* block */\n", 2},
+		// package ex ; func Sum ( a , b int ) int { return a + b }
+		{"package ex; func Sum(a, b int) int { return a + b }", 18},
+		{formattedCode, 21},
+		// long identifiers count as a single token
+		{"package ex\nvar averyveryverylongname = 1\n", 6},
+	}
+	for _, c := range cases {
+		n, err := CountTokens("ex.go", []byte(c.src))
+		if err != nil {
+			t.Fatalf("%q: %v", c.src, err)
+		}
+		if n != c.want {
+			t.Errorf("tokens of %q = %d, want %d", c.src, n, c.want)
+		}
+	}
+
+	if _, err := CountTokens("broken.go", []byte("package ex\nvar s = \"open\n")); err == nil {
+		t.Error("no error of unterminated string")
+	}
+}
@@ -0,0 +1,213 @@
+// Package exercism downloads published solutions of exercism exercises for Go,
+// benchmarks them and parses benchmark results.
+// It's the core of exercism-bench CLI usable as a library.
+package exercism
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Reducers of several benchmark runs.
+const (
+	ReduceMin    = "min"
+	ReduceMean   = "mean"
+	ReduceMedian = "median"
+)
+
+// Patterns of benchmark result line parts, e.g.
+// BenchmarkSum-8   	 5000000	       250 ns/op	  12.00 MB/s	      32 B/op	       1 allocs/op
+// Throughput is printed only if a benchmark sets bytes, mem stats only with -benchmem.
+const (
+	benchNamePattern       = "Benchmark([[:alnum:]]|_)+"
+	benchProcsPattern      = "-[[:digit:]]+"
+	benchIterationsPattern = "[[:digit:]]+"
+	benchTimePattern       = `([[:digit:]]+(\.[[:digit:]]+)?) ns/op`
+	benchThroughputPattern = `([[:digit:]]+(\.[[:digit:]]+)?) MB/s`
+	benchMemPattern        = `([[:digit:]]+) B/op\s+([[:digit:]]+) allocs/op`
+)
+
+var (
+	// BenchNameRE matches a benchmark name.
+	BenchNameRE       = regexp.MustCompile(benchNamePattern)
+	benchTimeRE       = regexp.MustCompile(benchTimePattern)
+	benchThroughputRE = regexp.MustCompile(benchThroughputPattern)
+	benchMemRE        = regexp.MustCompile(benchMemPattern)
+	benchStatsRE      = regexp.MustCompile(benchLinePattern())
+)
+
+// benchLinePattern builds a pattern of a whole benchmark result line from patterns of its parts.
+// Each part is a separate regexp to extract stats from a matched line.
+func benchLinePattern() string {
+	optional := func(p string) string {
+		return "(" + p + ")?"
+	}
+	return benchNamePattern + optional(benchProcsPattern) +
+		`\s+` + benchIterationsPattern +
+		`\s+` + benchTimePattern +
+		optional(`\s+`+benchThroughputPattern) +
+		optional(`\s+`+benchMemPattern)
+}
+
+// ErrNoBenchmarks is returned if go test output has no benchmark results.
+var ErrNoBenchmarks = errors.New("no benchmarks")
+
+// BenchStats are stats of a benchmark.
+// Throughput, Mem and Allocs are equal to -1 if they are absent.
+type BenchStats struct {
+	Time       float64   `json:"time"`       // ns
+	Throughput float64   `json:"throughput"` // MB/s
+	Mem        int64     `json:"mem"`        // B
+	Allocs     int64     `json:"allocs"`
+	Samples    []float64 `json:"samples"` // time of each run
+}
+
+// SolutionStats are stats of all benchmarks of a solution.
+type SolutionStats struct {
+	File       string                 `json:"file"`
+	Name       string                 `json:"name"`
+	GoVersion  string                 `json:"go_version"`
+	Size       uint                   `json:"size"`
+	Benchmarks map[string]*BenchStats `json:"benchmarks"`
+	Partial    bool                   `json:"partial,omitempty"`      // go test failed after some benchmarks
+	Failed     []string               `json:"failed_tests,omitempty"` // names of failed tests
+}
+
+// failedTestRE matches a line of a failed test or example with its name submatch.
+var failedTestRE = regexp.MustCompile(`(?m)^\s*--- FAIL: ((Test|Example)\S*)`)
+
+// FailedTests returns names of failed tests and examples (not benchmarks) found in go test output.
+func FailedTests(out string) (names []string) {
+	for _, ms := range failedTestRE.FindAllStringSubmatch(out, -1) {
+		names = append(names, ms[1])
+	}
+	return names
+}
+
+// ParseBenchOutput extracts stats from go test output.
+// go test prints a separate line for each benchmark run, so -count N gives N lines per benchmark.
+// All runs of a benchmark are reduced to a single stats entry by a given reducer.
+func ParseBenchOutput(out, reducer string) (bstats map[string]*BenchStats, err error) {
+	lines := benchStatsRE.FindAllString(out, -1)
+	if len(lines) == 0 {
+		return nil, ErrNoBenchmarks
+	}
+	runs := make(map[string][]*BenchStats)
+
+	for _, l := range lines {
+		st := &BenchStats{
+			Throughput: -1,
+			Mem:        -1,
+			Allocs:     -1,
+		}
+
+		// benchmark name
+		name := BenchNameRE.FindString(l)
+		// time
+		if ms := benchTimeRE.FindStringSubmatch(l); ms != nil {
+			st.Time, err = strconv.ParseFloat(ms[1], 64)
+			if err != nil {
+				return
+			}
+		} else {
+			return nil, fmt.Errorf("no time in %q", l)
+		}
+
+		// optional throughput
+		if ms := benchThroughputRE.FindStringSubmatch(l); ms != nil {
+			st.Throughput, err = strconv.ParseFloat(ms[1], 64)
+			if err != nil {
+				return
+			}
+		}
+
+		// optional mem
+		if ms := benchMemRE.FindStringSubmatch(l); ms != nil {
+			st.Mem, err = strconv.ParseInt(ms[1], 10, 64)
+			if err != nil {
+				return
+			}
+			st.Allocs, err = strconv.ParseInt(ms[2], 10, 64)
+			if err != nil {
+				return
+			}
+		}
+		runs[name] = append(runs[name], st)
+	}
+
+	bstats = make(map[string]*BenchStats, len(runs))
+	for name, sts := range runs {
+		bstats[name] = ReduceBenchStats(sts, reducer)
+	}
+	return bstats, nil
+}
+
+// ReduceBenchStats combines stats of several runs keeping time samples.
+// min reducer takes stats of the fastest run, mean and median ones reduce each stat separately.
+// Optional stats are kept absent if any run lacks them.
+func ReduceBenchStats(runs []*BenchStats, reducer string) *BenchStats {
+	st := &BenchStats{
+		Samples: make([]float64, 0, len(runs)),
+	}
+	for _, r := range runs {
+		st.Samples = append(st.Samples, r.Time)
+	}
+
+	if reducer == ReduceMin {
+		fastest := runs[0]
+		for _, r := range runs[1:] {
+			if r.Time < fastest.Time {
+				fastest = r
+			}
+		}
+		st.Time, st.Throughput, st.Mem, st.Allocs = fastest.Time, fastest.Throughput, fastest.Mem, fastest.Allocs
+		return st
+	}
+
+	var times, throughputs, mems, allocs []float64
+	for _, r := range runs {
+		times = append(times, r.Time)
+		throughputs = append(throughputs, r.Throughput)
+		mems = append(mems, float64(r.Mem))
+		allocs = append(allocs, float64(r.Allocs))
+	}
+	st.Time = ReduceValues(times, reducer)
+	st.Throughput = ReduceValues(throughputs, reducer)
+	st.Mem = int64(math.Round(ReduceValues(mems, reducer)))
+	st.Allocs = int64(math.Round(ReduceValues(allocs, reducer)))
+
+	for _, r := range runs {
+		if r.Throughput == -1 {
+			st.Throughput = -1
+		}
+		if r.Mem == -1 {
+			st.Mem = -1
+		}
+		if r.Allocs == -1 {
+			st.Allocs = -1
+		}
+	}
+	return st
+}
+
+// ReduceValues returns mean or median of values.
+func ReduceValues(vs []float64, reducer string) float64 {
+	if reducer == ReduceMedian {
+		sorted := append([]float64(nil), vs...)
+		sort.Float64s(sorted)
+		if len(sorted)%2 == 0 {
+			return (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+		}
+		return sorted[len(sorted)/2]
+	}
+
+	sum := 0.0
+	for _, v := range vs {
+		sum += v
+	}
+	return sum / float64(len(vs))
+}
@@ -0,0 +1,165 @@
+package exercism
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParseBenchOutputRepeated(t *testing.T) {
+	bs, err := ioutil.ReadFile("testdata/bench_count.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		reducer string
+		sum     BenchStats
+		bytes   BenchStats
+	}{
+		{ReduceMin, BenchStats{Time: 100, Throughput: -1, Mem: 16, Allocs: 1}, BenchStats{Time: 200, Throughput: 60}},
+		{ReduceMean, BenchStats{Time: 117.5, Throughput: -1, Mem: 20, Allocs: 1}, BenchStats{Time: 250, Throughput: 49.333333333333336}},
+		{ReduceMedian, BenchStats{Time: 115, Throughput: -1, Mem: 16, Allocs: 1}, BenchStats{Time: 250, Throughput: 48}},
+	}
+
+	for _, c := range cases {
+		bstats, err := ParseBenchOutput(string(bs), c.reducer)
+		if err != nil {
+			t.Fatalf("%s: %v", c.reducer, err)
+		}
+		if len(bstats) != 2 {
+			t.Fatalf("%s: got %d benchmarks, want 2", c.reducer, len(bstats))
+		}
+
+		// all runs are kept as samples in order
+		c.sum.Samples = []float64{120, 100, 140, 110}
+		c.bytes.Samples = []float64{300, 200, 250}
+		if got := bstats["BenchmarkSum"]; !reflect.DeepEqual(*got, c.sum) {
+			t.Errorf("%s: BenchmarkSum = %+v, want %+v", c.reducer, *got, c.sum)
+		}
+		if got := bstats["BenchmarkBytes"]; !reflect.DeepEqual(*got, c.bytes) {
+			t.Errorf("%s: BenchmarkBytes = %+v, want %+v", c.reducer, *got, c.bytes)
+		}
+	}
+}
+
+func FuzzParseBench(f *testing.F) {
+	bs, err := ioutil.ReadFile("testdata/bench_count.txt")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(string(bs))
+	f.Add("BenchmarkA-8 \t 1000 \t 12.5 ns/op \t 3.00 MB/s \t 16 B/op \t 1 allocs/op\n")
+	f.Add("BenchmarkA 1 99999999999999999999999 ns/op 1 B/op 99999999999999999999 allocs/op\n")
+	f.Add("BenchmarkA\nPASS\n")
+	f.Fuzz(func(t *testing.T, out string) {
+		for _, reducer := range []string{ReduceMin, ReduceMean, ReduceMedian} {
+			bstats, err := ParseBenchOutput(out, reducer)
+			if err != nil {
+				continue
+			}
+			if len(bstats) == 0 {
+				t.Fatalf("%s: no benchmarks without errors", reducer)
+			}
+			for name, st := range bstats {
+				if !BenchNameRE.MatchString(name) {
+					t.Errorf("%s: invalid benchmark name %q", reducer, name)
+				}
+				if st.Time < 0 || len(st.Samples) == 0 {
+					t.Errorf("%s: %s: invalid stats %+v", reducer, name, st)
+				}
+			}
+		}
+	})
+}
+
+// largeBenchOutput returns go test output of many benchmarks run several times with mixed optional stats.
+func largeBenchOutput() string {
+	var sb strings.Builder
+	sb.WriteString("goos: linux\ngoarch: amd64\npkg: ex\n")
+	for i := 0; i < 1000; i++ {
+		for run := 0; run < 5; run++ {
+			switch i % 3 {
+			case 0:
+				fmt.Fprintf(&sb, "BenchmarkOp%d-8   \t 1000000\t      %d.%d ns/op\n", i, 100+i, run)
+			case 1:
+				fmt.Fprintf(&sb, "BenchmarkOp%d-8   \t 1000000\t      %d ns/op\t      %d B/op\t       %d allocs/op\n", i, 100+run, i, run)
+			default:
+				fmt.Fprintf(&sb, "BenchmarkOp%d-8   \t  500000\t      %d ns/op\t  %d.50 MB/s\t      16 B/op\t       1 allocs/op\n", i, 200+run, i)
+			}
+		}
+	}
+	sb.WriteString("PASS\nok  \tex\t12.345s\n")
+	return sb.String()
+}
+
+func BenchmarkParseBenchOutput(b *testing.B) {
+	out := largeBenchOutput()
+	b.Run("regexp", func(b *testing.B) {
+		b.SetBytes(int64(len(out)))
+		for i := 0; i < b.N; i++ {
+			if len(benchStatsRE.FindAllString(out, -1)) != 5000 {
+				b.Fatal("unexpected number of lines")
+			}
+		}
+	})
+	for _, reducer := range []string{ReduceMin, ReduceMedian} {
+		b.Run(reducer, func(b *testing.B) {
+			b.SetBytes(int64(len(out)))
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseBenchOutput(out, reducer); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchLine is a result line of a benchmark setting bytes run with -benchmem.
+const benchLine = "BenchmarkSum_2-8   \t 5000000\t       250.5 ns/op\t  12.00 MB/s\t      32 B/op\t       1 allocs/op"
+
+func TestBenchREs(t *testing.T) {
+	cases := []struct {
+		name   string
+		re     *regexp.Regexp
+		in     string
+		groups map[int]string // expected submatches by index, nil if no match is expected
+	}{
+		{"line", benchStatsRE, benchLine, map[int]string{0: benchLine, 2: "-8", 3: "250.5", 6: "12.00", 9: "32", 10: "1"}},
+		{"line w/o throughput", benchStatsRE, "BenchmarkSum-8 \t 100\t 250 ns/op\t 32 B/op\t 1 allocs/op\n",
+			map[int]string{0: "BenchmarkSum-8 \t 100\t 250 ns/op\t 32 B/op\t 1 allocs/op", 3: "250", 6: "", 9: "32", 10: "1"}},
+		{"line w/o mem", benchStatsRE, "BenchmarkSum \t 100\t 250 ns/op\t 1.5 MB/s\n",
+			map[int]string{0: "BenchmarkSum \t 100\t 250 ns/op\t 1.5 MB/s", 2: "", 3: "250", 6: "1.5", 9: ""}},
+		{"line w/o newline", benchStatsRE, "BenchmarkSum-8 \t 100\t 250 ns/op", map[int]string{0: "BenchmarkSum-8 \t 100\t 250 ns/op", 3: "250"}},
+		{"line of -benchtime Nx", benchStatsRE, "BenchmarkSum-8 \t 20\t 0.2500 ns/op\n", map[int]string{3: "0.2500"}},
+		{"running line", benchStatsRE, "BenchmarkSum-8 \t--- FAIL: BenchmarkSum-8\n", nil},
+		{"line w/o time", benchStatsRE, "BenchmarkSum-8 \t 100\t 32 B/op\t 1 allocs/op", nil},
+		{"name", BenchNameRE, benchLine, map[int]string{0: "BenchmarkSum_2"}},
+		{"time", benchTimeRE, benchLine, map[int]string{0: "250.5 ns/op", 1: "250.5", 2: ".5"}},
+		{"time w/o dot", benchTimeRE, "250x5 ns/op", map[int]string{1: "5"}},
+		{"throughput", benchThroughputRE, benchLine, map[int]string{1: "12.00"}},
+		{"mem", benchMemRE, benchLine, map[int]string{1: "32", 2: "1"}},
+		{"failed test", failedTestRE, "=== RUN TestSum\n    --- FAIL: TestSum/case_1 (0.00s)\n", map[int]string{1: "TestSum/case_1", 2: "Test"}},
+		{"failed benchmark", failedTestRE, "--- FAIL: BenchmarkSum-8\n", nil},
+	}
+	for _, c := range cases {
+		ms := c.re.FindStringSubmatch(c.in)
+		if c.groups == nil {
+			if ms != nil {
+				t.Errorf("%s: unexpected match %q", c.name, ms)
+			}
+			continue
+		}
+		if ms == nil {
+			t.Errorf("%s: no match of %q", c.name, c.in)
+			continue
+		}
+		for i, want := range c.groups {
+			if ms[i] != want {
+				t.Errorf("%s: group %d = %q, want %q", c.name, i, ms[i], want)
+			}
+		}
+	}
+}
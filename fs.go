@@ -1,49 +1,60 @@
 package main
 
 import (
-	"errors"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-// copyFile copies only a regular file.
-func copyFile(srcPath, destPath string) error {
-	// check file type
-	fi, err := os.Stat(srcPath)
-	if err != nil {
+// writeFileAtomic writes data to a temp file in the same dir and renames it to path,
+// so the file is either fully written or absent if the write is interrupted.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return writeAtomic(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
 		return err
-	}
-	if !regular(fi) {
-		return errors.New("not a regular file")
-	}
+	})
+}
 
-	// copy data
-	bs, err := ioutil.ReadFile(srcPath)
+// writeAtomic works like writeFileAtomic but content is written by a given func.
+// The temp file is removed if it fails, an existing file at path is kept intact then.
+func writeAtomic(path string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(destPath, bs, fi.Mode())
-}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
 
-// copyFiles copies all files from srcDir to destDir.
-// All nested dirs are ignored.
-func copyFiles(srcDir, destDir string) error {
-	fis, err := ioutil.ReadDir(srcDir)
-	if err != nil {
+	if err = write(f); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
 		return err
 	}
+	return os.Rename(f.Name(), path)
+}
 
-	for _, fi := range fis {
-		if fi.IsDir() {
-			continue
+// sanitizeFileName replaces all symbols unsafe for a file name with underscores.
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' ||
+			(r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
 		}
-		n := fi.Name()
-		if err = copyFile(filepath.Join(srcDir, n), filepath.Join(destDir, n)); err != nil {
-			return err
-		}
-	}
-	return nil
+		return '_'
+	}, name)
 }
 
 func regular(fi os.FileInfo) bool {
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAtomicPartial(t *testing.T) {
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "solution.go")
+	old := "package ex\n\nfunc Sum(a, b int) int { return a + b }\n"
+	if err := writeFileAtomic(fp, []byte(old), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// an interrupted write leaves only a part of new content
+	code := "package ex\n\nfunc Sum(a, b int) int {\n\treturn b + a\n}\n"
+	err := writeAtomic(fp, 0600, func(w io.Writer) error {
+		if _, err := io.WriteString(w, code[:len(code)/2]); err != nil {
+			return err
+		}
+		return io.ErrShortWrite
+	})
+	if err != io.ErrShortWrite {
+		t.Fatalf("write error = %v, want %v", err, io.ErrShortWrite)
+	}
+
+	// the old file is intact and no temp files are left
+	bs, err := ioutil.ReadFile(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != old {
+		t.Errorf("file content = %q, want %q", bs, old)
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fis) != 1 {
+		t.Errorf("%d files left in dir, want only %s", len(fis), filepath.Base(fp))
+	}
+
+	// a new file is absent after an interrupted write
+	np := filepath.Join(dir, "new.go")
+	if err = writeAtomic(np, 0600, func(w io.Writer) error { return io.ErrShortWrite }); err == nil {
+		t.Fatal("no write error")
+	}
+	if _, err = ioutil.ReadFile(np); err == nil {
+		t.Errorf("%s exists after interrupted write", np)
+	}
+
+	if err = writeFileAtomic(fp, []byte(code), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if bs, err = ioutil.ReadFile(fp); err != nil || string(bs) != code {
+		t.Errorf("file content = %q, %v, want %q", bs, err, code)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const httpOnlyPrefix = "#HttpOnly_"
+
+// loadCookieJar creates a cookie jar with cookies from a Netscape format file.
+// The jar is empty if the file doesn't exist.
+func loadCookieJar(path string) (http.CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return jar, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// each line is: domain, include subdomains, path, secure, expiration, name, value
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		if httpOnly {
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fs := strings.Split(line, "\t")
+		if len(fs) != 7 {
+			return nil, fmt.Errorf("cookie jar %s line %d is invalid", path, n)
+		}
+		exp, err := strconv.ParseInt(fs[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cookie jar %s line %d has invalid expiration: %v", path, n, err)
+		}
+
+		c := &http.Cookie{
+			Name:     fs[5],
+			Value:    fs[6],
+			Path:     fs[2],
+			Secure:   fs[3] == "TRUE",
+			HttpOnly: httpOnly,
+		}
+		if exp != 0 {
+			c.Expires = time.Unix(exp, 0)
+		}
+		domain := strings.TrimPrefix(fs[0], ".")
+		if fs[1] == "TRUE" {
+			c.Domain = domain
+		}
+		scheme := "http"
+		if c.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: domain, Path: fs[2]}, []*http.Cookie{c})
+	}
+	if err = sc.Err(); err != nil {
+		return nil, err
+	}
+	return jar, nil
+}
+
+// saveCookieJar updates cookies of a site with a given address in a Netscape format file with ones from the jar.
+// Only names and values are known for cookies in the jar, so lines of other sites and attributes of existing cookies
+// are kept as is, values of existing cookies are updated, ones gone from the jar are removed
+// and new ones are stored as session ones.
+func saveCookieJar(path, addr string, jar http.CookieJar) error {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return err
+	}
+	bs, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	cookies := jar.Cookies(u)
+
+	sb := strings.Builder{}
+	if len(bs) == 0 {
+		sb.WriteString("# Netscape HTTP Cookie File\n")
+	}
+	for _, line := range strings.SplitAfter(string(bs), "\n") {
+		if line == "" {
+			continue
+		}
+		body := strings.TrimPrefix(line, httpOnlyPrefix)
+		fs := strings.Split(strings.TrimRight(body, "\r\n"), "\t")
+		if strings.HasPrefix(body, "#") || len(fs) != 7 || !cookieLineMatches(fs, u) {
+			sb.WriteString(line)
+			continue
+		}
+		// take a cookie with the same name out of the jar ones
+		i := 0
+		for i < len(cookies) && cookies[i].Name != fs[5] {
+			i++
+		}
+		if i == len(cookies) {
+			continue
+		}
+		fs[6] = cookies[i].Value
+		cookies = append(cookies[:i], cookies[i+1:]...)
+		if strings.HasPrefix(line, httpOnlyPrefix) {
+			sb.WriteString(httpOnlyPrefix)
+		}
+		sb.WriteString(strings.Join(fs, "\t") + "\n")
+	}
+	for _, c := range cookies {
+		fmt.Fprintf(&sb, "%s\tFALSE\t/\t%s\t0\t%s\t%s\n",
+			u.Hostname(), strings.ToUpper(strconv.FormatBool(u.Scheme == "https")), c.Name, c.Value)
+	}
+	return writeFileAtomic(path, []byte(sb.String()), 0600)
+}
+
+// cookieLineMatches checks whether a cookie with fields of a file line is sent to a given URL.
+func cookieLineMatches(fs []string, u *url.URL) bool {
+	host, domain := u.Hostname(), strings.TrimPrefix(fs[0], ".")
+	if host != domain && (fs[1] != "TRUE" || !strings.HasSuffix(host, "."+domain)) {
+		return false
+	}
+	if fs[3] == "TRUE" && u.Scheme != "https" {
+		return false
+	}
+	p, cp := u.Path, fs[2]
+	if p == "" {
+		p = "/"
+	}
+	return p == cp || (strings.HasPrefix(p, cp) && (strings.HasSuffix(cp, "/") || p[len(cp)] == '/'))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveCookieJarPreserves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	jarFile := "# Netscape HTTP Cookie File\n" +
+		"# a comment\n" +
+		".other.org\tTRUE\t/\tTRUE\t4102444800\tsid\tother\n" +
+		"#HttpOnly_.site.io\tTRUE\t/\tTRUE\t4102444800\tsid\told\n" +
+		"site.io\tFALSE\t/\tTRUE\t4102444800\tgone\tx\n" +
+		"site.io\tFALSE\t/admin\tTRUE\t4102444800\tadmin\ty\n" +
+		"site.io\tFALSE\t/\tTRUE\t0\tkept\tz\n"
+	if err := ioutil.WriteFile(path, []byte(jarFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	jar, err := loadCookieJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the site updates one cookie, deletes another one and sets a new one
+	u := &url.URL{Scheme: "https", Host: "site.io", Path: "/"}
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "sid", Value: "new", Domain: "site.io", Path: "/"},
+		{Name: "gone", Value: "", Path: "/", MaxAge: -1},
+		{Name: "fresh", Value: "w", Path: "/"},
+	})
+	if err = saveCookieJar(path, "https://site.io", jar); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Netscape HTTP Cookie File\n" +
+		"# a comment\n" +
+		".other.org\tTRUE\t/\tTRUE\t4102444800\tsid\tother\n" +
+		"#HttpOnly_.site.io\tTRUE\t/\tTRUE\t4102444800\tsid\tnew\n" +
+		"site.io\tFALSE\t/admin\tTRUE\t4102444800\tadmin\ty\n" +
+		"site.io\tFALSE\t/\tTRUE\t0\tkept\tz\n" +
+		"site.io\tFALSE\t/\tTRUE\t0\tfresh\tw\n"
+	if string(bs) != want {
+		t.Errorf("cookie jar file:\n%s\nwant:\n%s", bs, want)
+	}
+
+	// a saved jar loads back
+	if _, err = loadCookieJar(path); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSaveCookieJarNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	jar, err := loadCookieJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar.SetCookies(&url.URL{Scheme: "http", Host: "site.io", Path: "/"}, []*http.Cookie{{Name: "sid", Value: "v"}})
+	if err = saveCookieJar(path, "http://site.io", jar); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Netscape HTTP Cookie File\nsite.io\tFALSE\t/\tFALSE\t0\tsid\tv\n"; string(bs) != want {
+		t.Errorf("cookie jar file:\n%s\nwant:\n%s", bs, want)
+	}
+}
@@ -1,142 +1,630 @@
 package main
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
 	"io/ioutil"
 	"log"
+	"math/rand"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/avegner/exercism-bench/exercism"
 )
 
 const (
-	exercismAddr = "https://exercism.io"
-	trackLang    = "go"
+	trackLang             = "go"
+	benchRetryDelay       = time.Second
+	downloadVerifyRetries = 2
+	// solutionsBenchSource is a -benchname-source value for solution files of solutions dir,
+	// it isn't a plain dir name to not shadow ./solutions, the default solutions dir.
+	solutionsBenchSource = ":solutions"
 )
 
-var commands = map[string]func(tq chan<- task, args []string) error{
-	"total":    totalCmd,
-	"download": downloadCmd,
-	"bench":    benchCmd,
-	"clean":    cleanCmd,
+var commands = map[string]func(ctx context.Context, cfg *config, tq chan<- task, args []string) error{
+	"total":        totalCmd,
+	"download":     downloadCmd,
+	"suite":        suiteCmd,
+	"bench":        benchCmd,
+	"clean":        cleanCmd,
+	"verify":       verifyCmd,
+	"report":       reportCmd,
+	"significance": significanceCmd,
+	"debug-page":   debugPageCmd,
+	"top-authors":  topAuthorsCmd,
+	"share":        shareCmd,
+	"watch":        watchCmd,
+}
+
+// interruptExitCommands run until an interrupt, which is their normal exit.
+var interruptExitCommands = map[string]bool{
+	"watch": true,
 }
 
 var (
-	exercise        = ""
-	downloadDirFlag = "./solutions"
-	concurrencyFlag = false
-	maxProcsFlag    = runtime.GOMAXPROCS(0)
+	downloadDirFlag    = "./solutions"
+	addrFlag           = exercism.DefaultAddr
+	concurrencyFlag    = false
+	workersFlag        = 0
+	perHostFlag        = 0
+	offlineFlag        = false
+	cookieJarFlag      = ""
+	caCertFlag         = ""
+	pageCacheFlag      = false
+	apiFlag            = false
+	apiURLFlag         = defaultAPIURL
+	apiTokenFlag       = ""
+	sinceFlag          = ""
+	benchSourcesFlag   = stringsFlag{}
+	insecureFlag       = false
+	maxProcsFlag       = runtime.GOMAXPROCS(0)
+	suiteDirFlag       = ""
+	includeFlag        = stringsFlag{}
+	keepCRLFFlag       = false
+	logDirFlag         = ""
+	maxAllocsFlag      = int64(-1)
+	allocsRatioFlag    = 0.0
+	minTimeFlag        = 0.0
+	minNsFlag          = 0.0
+	dropSuspectFlag    = false
+	maxTimeFlag        = 0.0
+	noSizeCacheFlag    = false
+	benchMemFlag       = true
+	benchMaxProcsFlag  = 1
+	failFastFlag       = false
+	sortBySizeFlag     = true
+	sortKeysFlag       = ""
+	precisionFlag      = 1
+	unitsFlag          = "ns"
+	noColorFlag        = false
+	progressFileFlag   = ""
+	progressFDFlag     = -1
+	progressStepFlag   = 0.0
+	verifyDownloadFlag = false
+	verboseFlag        = false
+	benchRetriesFlag   = 0
+	countFlag          = 1
+	benchtimeFlag      = time.Duration(0)
+	tagsFlag           = ""
+	flakyFlag          = 0.0
+	reduceFlag         = "median"
+	refreshSuiteFlag   = false
+	strictSuiteFlag    = false
+	httpRetriesFlag    = 0
+	pageTimeoutFlag    = httpTimeout
+	solTimeoutFlag     = httpTimeout
+	backoffBaseFlag    = 200 * time.Millisecond
+	backoffMaxFlag     = 30 * time.Second
+	perAuthorFlag      = 0
+	goBinsFlag         = stringsFlag{}
+	envFlag            = stringsFlag{}
+	baselineFlag       = ""
+	saveFlag           = ""
+	appendFlag         = false
+	topFlag            = 10
+	gistFlag           = false
+	gistTokenFlag      = ""
+	dataURLFlag        = ""
+	jsonFlag           = false
+	compareFileFlag    = ""
+	nameTemplateFlag   = defaultNameTemplate
+	layoutFlag         = "flat"
+	bySolutionFlag     = false
+	percentileFlag     = false
+	showEmptyFlag      = false
+	shuffleFlag        = false
+	roundsFlag         = 1
+	cooldownFlag       = time.Duration(0)
+	skipFailingFlag    = false
+	runTestsFlag       = false
+	showSamplesFlag    = false
+	onlyWithMemFlag    = false
+	allocRateFlag      = false
+	seedFlag           = int64(0)
+	regressFlag        = 5.0
+	csvFlag            = false
+	alphaFlag          = 0.05
+	normalizeFlag      = false
+	sizeMetricFlag     = "symbols"
+	canonicalDedupFlag = false
+	overallFlag        = false
+	dbFlag             = ""
+	streamFlag         = ""
+	lowMemFlag         = false
 )
 
 var (
-	solutionPathRE         = regexp.MustCompile("solutions/(([[:xdigit:]][[:xdigit:]]){16})")
-	solutionGroupsNumberRE = regexp.MustCompile(`solutions\?page=([[:digit:]]+)">Last`)
+	solutionPathRE        = exercism.SolutionPathRE
+	uuidRE                = regexp.MustCompile(`^([[:xdigit:]][[:xdigit:]]){16}$`)
+	solutionFileNameRE    = regexp.MustCompile(`^(([[:xdigit:]][[:xdigit:]]){16})-(.+)$`)
+	exerciseSlugRE        = regexp.MustCompile(`/tracks/` + trackLang + `/exercises/([[:alnum:]-]+)`)
+	exercisePagesNumberRE = regexp.MustCompile(`exercises\?page=([[:digit:]]+)">Last`)
 )
 
-var errInvalidUsage = errors.New("invalid usage")
+// trackCommands don't need an exercise name.
+var trackCommands = map[string]func(ctx context.Context, cfg *config, tq chan<- task, args []string) error{
+	"exercises": exercisesCmd,
+}
+
+var (
+	errInvalidUsage = errors.New("invalid usage")
+	errInterrupted  = errors.New("interrupted")
+)
 
 var mlog = log.New(os.Stderr, "", 0)
 
+// notifyContext is replaced in tests to simulate an interrupt.
+var notifyContext = signal.NotifyContext
+
+// vlogf logs only in verbose mode.
+func vlogf(format string, v ...interface{}) {
+	if verboseFlag {
+		mlog.Printf(format, v...)
+	}
+}
+
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), `Usage: %s [flag...] <exercise-name> <command>
+		fmt.Fprintf(flag.CommandLine.Output(), `Usage: %[1]s [flag...] <exercise-name> <command>
+       %[1]s [flag...] <track-command>
+
+Track commands:
+  exercises
+  	list available exercises
 
 Commands:
   total
   	calculate number of published solutions
   download
-  	download published solutions
+  	download published solutions (existing test suite is kept)
+  suite
+  	download test suite only
   bench
   	bench downloaded solutions
   clean
   	remove downloaded solutions
+  verify
+  	check downloaded solutions and test suite can be parsed
+  report <results>
+  	print sorted stats of saved or streamed results
+  significance <old-results> <new-results>
+  	compare time samples of saved results with Mann-Whitney U test
+  watch
+  	bench solution files on their changes until interrupted
+  top-authors [results]
+  	rank authors of downloaded solutions by top places in saved results and solutions number
+  share <results> <benchmark> [rank]
+  	print Markdown snippet with stats and code of solution with rank (1 by default) in saved results
+  debug-page [uuid] [file]
+  	write raw solution page or first solutions group page w/o uuid to stdout or a file
 
 Flags:
 `, filepath.Base(os.Args[0]))
 		flag.PrintDefaults()
 	}
+	flag.StringVar(&addrFlag, "addr", addrFlag, "address of exercism site or its mirror")
 	flag.StringVar(&downloadDirFlag, "d", downloadDirFlag, "directory to store solutions")
-	flag.BoolVar(&concurrencyFlag, "c", concurrencyFlag, "enable concurrency")
+	flag.BoolVar(&concurrencyFlag, "c", concurrencyFlag, "enable concurrency (workers = GOMAXPROCS)")
+	flag.IntVar(&workersFlag, "workers", workersFlag, "number of workers, overrides -c (0 - 1 or GOMAXPROCS with -c)")
+	flag.StringVar(&caCertFlag, "cacert", caCertFlag, "PEM file with a custom root CA to trust, e.g. of TLS intercepting proxy")
+	flag.BoolVar(&insecureFlag, "insecure", insecureFlag, "skip TLS certificate verification (last resort, unsafe)")
+	flag.StringVar(&cookieJarFlag, "cookie-jar", cookieJarFlag, "Netscape format cookie jar file to load cookies from and save them to")
+	flag.BoolVar(&pageCacheFlag, "page-cache", pageCacheFlag, "cache downloaded pages on disk and reuse them instead of network requests")
+	flag.BoolVar(&apiFlag, "api", apiFlag, "download solutions with JSON API instead of scraping site pages (needs -api-token)")
+	flag.StringVar(&apiURLFlag, "api-url", apiURLFlag, "base URL of JSON API")
+	flag.StringVar(&apiTokenFlag, "api-token", apiTokenFlag, "token of JSON API")
+	flag.BoolVar(&offlineFlag, "offline", offlineFlag, "use only local files, commands requiring network fail")
+	flag.IntVar(&perHostFlag, "per-host", perHostFlag, "max number of concurrent requests to a host (0 - unlimited)")
 	flag.IntVar(&maxProcsFlag, "mp", maxProcsFlag, "GOMAXPROCS value to set")
+	flag.StringVar(&suiteDirFlag, "suite", suiteDirFlag, "directory with custom test suite to bench against")
+	flag.BoolVar(&keepCRLFFlag, "keep-crlf", keepCRLFFlag, "keep original line endings in downloaded code")
+	flag.StringVar(&logDirFlag, "log-dir", logDirFlag, "directory to store raw go test output of each solution")
+	flag.Int64Var(&maxAllocsFlag, "max-allocs", maxAllocsFlag, "flag solutions with more allocs per op (-1 to disable)")
+	flag.Float64Var(&allocsRatioFlag, "allocs-ratio", allocsRatioFlag,
+		"flag solutions with allocs per op above median multiplied by the ratio (0 to disable)")
+	flag.Float64Var(&minTimeFlag, "min-time", minTimeFlag, "print only solutions with time not less than this ns value (0 to disable)")
+	flag.Float64Var(&minNsFlag, "min-ns", minNsFlag, "tag solutions with time below this ns value as SUSPECT of dead code elimination (0 to disable)")
+	flag.BoolVar(&dropSuspectFlag, "drop-suspect", dropSuspectFlag, "exclude SUSPECT solutions from ranking (needs -min-ns)")
+	flag.Float64Var(&maxTimeFlag, "max-time", maxTimeFlag, "print only solutions with time not greater than this ns value (0 to disable)")
+	flag.BoolVar(&noSizeCacheFlag, "no-size-cache", noSizeCacheFlag, "don't use cached code sizes")
+	flag.BoolVar(&benchMemFlag, "benchmem", benchMemFlag, "collect memory allocation stats")
+	flag.IntVar(&benchMaxProcsFlag, "bench-mp", benchMaxProcsFlag, "GOMAXPROCS value to set for benchmarks (0 to inherit)")
+	flag.BoolVar(&failFastFlag, "fail-fast", failFastFlag, "stop bench at the first failed solution")
+	flag.BoolVar(&sortBySizeFlag, "include-size-in-sort", sortBySizeFlag, "use code size as the last sort key")
+	flag.StringVar(&sortKeysFlag, "sort-keys", sortKeysFlag, "comma-separated sort keys in order of precedence: time, throughput, mem, allocs, size, alloc-rate (overrides -include-size-in-sort)")
+	flag.IntVar(&precisionFlag, "precision", precisionFlag, "number of decimals to print for time and throughput")
+	flag.StringVar(&unitsFlag, "units", unitsFlag, "time units to print: auto or ns")
+	flag.BoolVar(&noColorFlag, "no-color", noColorFlag, "disable colored output (NO_COLOR env variable is respected too)")
+	flag.StringVar(&progressFileFlag, "progress-file", progressFileFlag, "file to write JSON lines with progress to")
+	flag.Float64Var(&progressStepFlag, "progress-step", progressStepFlag, "log progress only when it crosses a step in percents, other items are logged in verbose mode (0 - log every item)")
+	flag.IntVar(&progressFDFlag, "progress-fd", progressFDFlag, "file descriptor to write JSON lines with progress to (-1 to disable)")
+	flag.BoolVar(&verboseFlag, "v", verboseFlag, "enable verbose logging")
+	flag.IntVar(&benchRetriesFlag, "bench-retries", benchRetriesFlag, "number of retries of a failed solution bench")
+	flag.IntVar(&countFlag, "count", countFlag, "number of runs of each benchmark")
+	flag.DurationVar(&benchtimeFlag, "benchtime", benchtimeFlag, "run time of each benchmark (0 - go test default)")
+	flag.StringVar(&tagsFlag, "tags", tagsFlag, "comma-separated build tags for benchmarks and their discovery")
+	flag.Float64Var(&flakyFlag, "flaky-threshold", flakyFlag,
+		"flag solutions with coefficient of variation of time samples above the threshold (0 to disable)")
+	flag.StringVar(&reduceFlag, "reduce", reduceFlag, "reducer of benchmark runs: min, mean or median")
+	flag.BoolVar(&verifyDownloadFlag, "verify-download", verifyDownloadFlag, "check downloaded solutions parse and refetch them otherwise")
+	flag.BoolVar(&refreshSuiteFlag, "refresh-suite", refreshSuiteFlag, "make download command refresh only test suite like suite command, solutions are kept")
+	flag.BoolVar(&strictSuiteFlag, "strict-suite", strictSuiteFlag, "fail bench if test suite differs from one solutions were downloaded against")
+	flag.IntVar(&httpRetriesFlag, "http-retries", httpRetriesFlag, "number of retries of a failed HTTP request")
+	flag.DurationVar(&pageTimeoutFlag, "page-timeout", pageTimeoutFlag, "timeout of a request of exercises or solution groups page (0 - HTTP timeout)")
+	flag.DurationVar(&solTimeoutFlag, "solution-timeout", solTimeoutFlag, "timeout of a request of solution page (0 - HTTP timeout)")
+	flag.DurationVar(&backoffBaseFlag, "backoff-base", backoffBaseFlag, "base delay of exponential backoff between HTTP retries")
+	flag.DurationVar(&backoffMaxFlag, "backoff-max", backoffMaxFlag, "max delay of exponential backoff between HTTP retries")
+	flag.StringVar(&sinceFlag, "since", sinceFlag, "bench only solutions downloaded since a time (RFC 3339 or date) or a duration ago")
+	flag.IntVar(&perAuthorFlag, "per-author", perAuthorFlag, "max number of solutions per author to bench (0 for no limit)")
+	flag.Var(&goBinsFlag, "go", "go `binary` to bench with (repeatable, go from PATH by default)")
+	flag.Var(&envFlag, "env", "`KEY=VAL` environment variable to set for benchmarks (repeatable)")
+	flag.StringVar(&baselineFlag, "baseline", baselineFlag, "solution file name or UUID to print stats ratios to")
+	flag.StringVar(&saveFlag, "save", saveFlag, "file to save bench results to")
+	flag.StringVar(&dataURLFlag, "data-url", dataURLFlag, "base URL to download data files referenced by test suite from as <url>/<exercise>/<path> (empty to disable)")
+	flag.BoolVar(&allocRateFlag, "alloc-rate", allocRateFlag, "print alloc rate (mem / time in B/ns) column")
+	flag.BoolVar(&onlyWithMemFlag, "only-with-mem", onlyWithMemFlag, "report only solutions with mem stats of a benchmark (requires -benchmem for bench)")
+	flag.BoolVar(&showSamplesFlag, "show-samples", showSamplesFlag, "print time of each -count run under solution stats")
+	flag.BoolVar(&runTestsFlag, "run-tests", runTestsFlag, "run tests before benchmarks as a correctness gate (only benchmarks run by default)")
+	flag.BoolVar(&skipFailingFlag, "skip-failing-tests", skipFailingFlag, "exclude solutions with failed tests from results (needs -run-tests)")
+	flag.DurationVar(&cooldownFlag, "cooldown", cooldownFlag, "pause before each solution bench except the first one to reduce thermal throttling")
+	flag.IntVar(&roundsFlag, "rounds", roundsFlag, "number of rounds benching every solution once, results are reduced across rounds")
+	flag.BoolVar(&shuffleFlag, "shuffle", shuffleFlag, "bench solutions in random order to spread time-dependent noise")
+	flag.Int64Var(&seedFlag, "seed", seedFlag, "seed of shuffle to repeat an order (0 - random)")
+	flag.BoolVar(&showEmptyFlag, "show-empty", showEmptyFlag, "print benchmarks w/o results of any solution")
+	flag.BoolVar(&percentileFlag, "percentile", percentileFlag, "print percent of other solutions each one is faster than")
+	flag.BoolVar(&bySolutionFlag, "by-solution", bySolutionFlag, "print ranks of each solution across benchmarks marking its worst one")
+	flag.StringVar(&layoutFlag, "layout", layoutFlag, "layout of downloaded solutions: flat or nested (a dir per solution)")
+	flag.StringVar(&nameTemplateFlag, "name-template", nameTemplateFlag, "text/template of downloaded solution file names with .UUID and .Author fields")
+	flag.StringVar(&compareFileFlag, "compare-baseline-file", compareFileFlag, "saved results to compare time of benched solutions with, bench fails on regressions")
+	flag.Float64Var(&regressFlag, "regress-threshold", regressFlag, "time increase in percents over compare baseline file treated as regression")
+	flag.BoolVar(&jsonFlag, "json", jsonFlag, "print total as JSON object to stdout")
+	flag.BoolVar(&csvFlag, "csv", csvFlag, "print total as CSV with a header to stdout")
+	flag.IntVar(&topFlag, "top", topFlag, "number of top places counted by top-authors command")
+	flag.BoolVar(&gistFlag, "gist", gistFlag, "upload snippet of share command to a secret GitHub gist (needs -gist-token)")
+	flag.StringVar(&gistTokenFlag, "gist-token", gistTokenFlag, "GitHub token with gist scope")
+	flag.BoolVar(&appendFlag, "append", appendFlag, "merge results into existing save file benching only new or changed solutions")
+	flag.Float64Var(&alphaFlag, "alpha", alphaFlag, "p-value threshold of significant differences")
+	flag.BoolVar(&normalizeFlag, "normalize", normalizeFlag, "gofmt solution code before size counting")
+	flag.StringVar(&sizeMetricFlag, "size-metric", sizeMetricFlag, "code size metric: symbols or tokens")
+	flag.BoolVar(&canonicalDedupFlag, "canonical-dedup", canonicalDedupFlag,
+		"bench only one of solutions identical except comments, formatting and local names")
+	flag.BoolVar(&overallFlag, "overall", overallFlag, "print overall leaderboard by average rank across benchmarks")
+	flag.StringVar(&dbFlag, "db", dbFlag, "SQLite database file to write bench results to")
+	flag.StringVar(&streamFlag, "stream", streamFlag, "file to write bench results to as JSON lines as soon as they are ready")
+	flag.BoolVar(&lowMemFlag, "low-mem", lowMemFlag, "keep bench results in temp file instead of memory and sort them one benchmark at a time")
+	flag.Var(&benchSourcesFlag, "benchname-source", "extra dir to look for benchmark names in its test files or "+solutionsBenchSource+" for solution files (repeatable)")
+	flag.Var(&includeFlag, "include", "extra `file` to include in every bench build (repeatable)")
 	flag.Parse()
+	initColor()
 
 	if err := run(flag.Args()); err != nil {
 		if err == errInvalidUsage {
 			flag.Usage()
 			os.Exit(2)
 		}
-		mlog.Printf("run error: %v", err)
+		mlog.Print(colorize(fmt.Sprintf("run error: %v", err), colorRed))
 		os.Exit(1)
 	}
 }
 
 func run(args []string) (err error) {
 	// check args
-	if len(args) < 2 {
+	if len(args) < 1 {
 		return errInvalidUsage
 	}
-	exercise = args[0]
-	cmd, ok := commands[args[1]]
-	if !ok {
+	if countFlag < 1 || benchtimeFlag < 0 || precisionFlag < 0 || workersFlag < 0 || perHostFlag < 0 || (unitsFlag != "auto" && unitsFlag != "ns") ||
+		minNsFlag < 0 || (dropSuspectFlag && minNsFlag == 0) || (skipFailingFlag && !runTestsFlag) ||
+		(reduceFlag != "min" && reduceFlag != "mean" && reduceFlag != "median") ||
+		(sizeMetricFlag != "symbols" && sizeMetricFlag != "tokens") ||
+		(layoutFlag != "flat" && layoutFlag != "nested") {
 		return errInvalidUsage
 	}
+	for _, e := range envFlag {
+		if !strings.Contains(e, "=") {
+			return errInvalidUsage
+		}
+		if v := strings.TrimPrefix(e, "GOMAXPROCS="); v != e {
+			if n, err := strconv.Atoi(v); err != nil || n < 1 {
+				return errInvalidUsage
+			}
+		}
+	}
+	if nameTemplate, err = parseNameTemplate(nameTemplateFlag); err != nil {
+		return err
+	}
+	if sortKeysFlag != "" {
+		if err = checkSortKeys(sortKeysFlag); err != nil {
+			return err
+		}
+	}
+	cfg := &config{
+		dir:  downloadDirFlag,
+		addr: strings.TrimSuffix(addrFlag, "/"),
+		// requests are limited by per request timeouts
+		client: &http.Client{},
+	}
+	cmdName := args[0]
+	cmd, ok := trackCommands[cmdName]
+	cmdArgs := args[1:]
+	if !ok {
+		if len(args) < 2 {
+			return errInvalidUsage
+		}
+		cfg.exercise = args[0]
+		cmdName = args[1]
+		if cmd, ok = commands[cmdName]; !ok {
+			return errInvalidUsage
+		}
+		cmdArgs = args[2:]
+	}
+
+	// open progress sink
+	if err = openProgress(); err != nil {
+		return err
+	}
+	defer closeProgress()
+
+	// set up HTTP client
+	if err = configureTLS(cfg.client); err != nil {
+		return err
+	}
+	if err = configureAPI(cfg); err != nil {
+		return err
+	}
+
+	// load cookies
+	if cookieJarFlag != "" {
+		if cfg.client.Jar, err = loadCookieJar(cookieJarFlag); err != nil {
+			return err
+		}
+		defer func() {
+			if err := saveCookieJar(cookieJarFlag, cfg.addr, cfg.client.Jar); err != nil {
+				mlog.Printf("cookie jar save error: %v", err)
+			}
+		}()
+	}
 
 	// determine task queue size
 	tqSize := 1
 	runtime.GOMAXPROCS(maxProcsFlag)
-	if concurrencyFlag {
+	if workersFlag > 0 {
+		tqSize = workersFlag
+	} else if concurrencyFlag {
 		tqSize = runtime.GOMAXPROCS(0)
 	}
 
 	// create task queue and pool of general purpose workers
-	tq := make(chan task, tqSize)
-	defer close(tq)
-	for i := 0; i < tqSize; i++ {
-		go worker(tq)
-	}
+	tq, stop := startWorkers(tqSize)
+	defer stop()
+	cfg.source = newSource(cfg, tq)
+
+	// cancel a command on interrupt, processes it runs are in their own groups and don't get terminal signals
+	// a repeated interrupt stops the tool at once
+	ctx, stopSignals := notifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	go func() {
+		<-ctx.Done()
+		stopSignals()
+	}()
 
 	// run a given command
-	return cmd(tq, args[2:])
+	if err = cmd(ctx, cfg, tq, cmdArgs); err == nil && ctx.Err() != nil && !interruptExitCommands[cmdName] {
+		err = errInterrupted
+	}
+	return err
+}
+
+// stringsFlag is a repeatable string flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
 }
 
 type task func()
 
-func worker(wq <-chan task) {
+// startWorkers starts a pool of n general purpose workers and returns its task queue.
+// The queue is unbounded, so a task can enqueue further tasks without deadlocking
+// even if all workers are busy.
+// stop waits until all queued and running tasks are done and then stops the workers,
+// so no task can send to the queue after it's closed.
+func startWorkers(n int) (tq chan<- task, stop func()) {
+	q := make(chan task)
+	wq := make(chan task)
+	doneq := make(chan struct{})
+	stopq := make(chan struct{})
+	stopped := make(chan struct{})
+
+	// dispatch queued tasks to workers in order and track running ones
+	go func() {
+		defer close(stopped)
+		defer close(wq)
+		pending := []task{}
+		running := 0
+		stopping := false
+		for !stopping || running != 0 || len(pending) != 0 {
+			var (
+				next task
+				out  chan task
+				sq   = stopq
+			)
+			if len(pending) != 0 {
+				next, out = pending[0], wq
+			}
+			if stopping {
+				sq = nil
+			}
+			select {
+			case t := <-q:
+				pending = append(pending, t)
+			case out <- next:
+				pending[0] = nil
+				pending = pending[1:]
+				running++
+			case <-doneq:
+				running--
+			case <-sq:
+				stopping = true
+			}
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		go worker(wq, doneq)
+	}
+	return q, func() {
+		close(stopq)
+		<-stopped
+		close(q)
+	}
+}
+
+func worker(wq <-chan task, doneq chan<- struct{}) {
 	for {
 		t, ok := <-wq
 		if !ok {
 			return
 		}
 		t()
+		doneq <- struct{}{}
 	}
 }
 
-func totalCmd(tq chan<- task, args []string) error {
+func exercisesCmd(ctx context.Context, cfg *config, tq chan<- task, args []string) error {
 	if len(args) != 0 {
 		return errInvalidUsage
 	}
 
-	uuids, err := getSolutionUUIDs(tq)
+	slugs, err := getExerciseSlugs(ctx, cfg, tq)
 	if err != nil {
 		return err
 	}
-	mlog.Printf("solutions total: %d", len(uuids))
+	for _, s := range slugs {
+		mlog.Println(s)
+	}
+	mlog.Println()
+	mlog.Printf("exercises total: %d", len(slugs))
+
+	return nil
+}
+
+func totalCmd(ctx context.Context, cfg *config, tq chan<- task, args []string) error {
+	if len(args) != 0 || (jsonFlag && csvFlag) {
+		return errInvalidUsage
+	}
+
+	hits, misses := atomic.LoadInt64(&pageCacheHits), atomic.LoadInt64(&pageCacheMisses)
+	uuids, err := cfg.source.ListUUIDs(ctx)
+	if err != nil {
+		return err
+	}
+	hits, misses = atomic.LoadInt64(&pageCacheHits)-hits, atomic.LoadInt64(&pageCacheMisses)-misses
+
+	// report source of the count
+	source := "network"
+	if hits != 0 && misses == 0 {
+		source = "page cache"
+	} else if hits != 0 {
+		source = "page cache and network"
+	}
+	switch {
+	case jsonFlag:
+		return json.NewEncoder(os.Stdout).Encode(struct {
+			Exercise string `json:"exercise"`
+			Total    int    `json:"total"`
+			Source   string `json:"source"`
+		}{cfg.exercise, len(uuids), source})
+	case csvFlag:
+		return csv.NewWriter(os.Stdout).WriteAll([][]string{
+			{"exercise", "total", "source"},
+			{cfg.exercise, strconv.Itoa(len(uuids)), source},
+		})
+	}
+	mlog.Printf("solutions total: %d (from %s)", len(uuids), source)
 
 	return nil
 }
 
-func benchCmd(tq chan<- task, args []string) error {
+func benchCmd(ctx context.Context, cfg *config, tq chan<- task, args []string) error {
 	if len(args) != 0 {
 		return errInvalidUsage
 	}
 
+	if (streamFlag != "" || lowMemFlag) && (saveFlag != "" || dbFlag != "") {
+		return errors.New("results stream and low memory mode can't be combined with save or db")
+	}
+	if roundsFlag < 1 || cooldownFlag < 0 {
+		return errInvalidUsage
+	}
+	if onlyWithMemFlag && !benchMemFlag {
+		return errors.New("only with mem mode requires benchmem")
+	}
+	if (streamFlag != "" || lowMemFlag) && roundsFlag > 1 {
+		return errors.New("results stream and low memory mode can't be combined with rounds")
+	}
+	if (streamFlag != "" || lowMemFlag) && compareFileFlag != "" {
+		return errors.New("results stream and low memory mode can't be combined with compare baseline file")
+	}
+	if appendFlag && saveFlag == "" {
+		return errors.New("append mode requires save file")
+	}
+
+	// check test suite
+	tsDir := testSuiteDir(cfg)
+	if err := checkTestSuite(tsDir); err != nil {
+		if os.IsNotExist(err) && suiteDirFlag == "" {
+			return errors.New("no test suite found; run 'download' first")
+		}
+		return err
+	}
+	if err := typeCheckTestSuite(tsDir); err != nil {
+		return err
+	}
+	// a custom suite differs from the downloaded one on purpose
+	if suiteDirFlag != "" {
+		vlogf("custom test suite is set, its hash check skipped")
+	} else if err := checkSuiteHash(cfg, tsDir); err != nil {
+		if strictSuiteFlag {
+			return err
+		}
+		mlog.Print(colorize(fmt.Sprintf("warning: %v", err), colorYellow))
+	}
+
 	// get benchmark names
-	bnames, err := getBenchNames(solutionsDir("test-suite"))
+	bsrcs := []string{tsDir}
+	solsDir := ""
+	for _, src := range benchSourcesFlag {
+		if src == solutionsBenchSource {
+			solsDir = cfg.solutionsDir()
+			continue
+		}
+		bsrcs = append(bsrcs, src)
+	}
+	bnames, err := getBenchNames(solsDir, bsrcs...)
 	if err != nil {
 		return err
 	}
@@ -150,97 +638,395 @@ func benchCmd(tq chan<- task, args []string) error {
 	mlog.Println()
 
 	// get solutions total
-	fis, err := ioutil.ReadDir(solutionsDir())
+	fnames, err := listSolutionFiles(cfg)
 	if err != nil {
 		return err
 	}
-	// TODO: replace with a precise count of solutions?
-	// by default all files except test suite dir are considered as a solution code
-	total := len(fis) - 1
-	if total <= 0 {
+	if sinceFlag != "" {
+		since, err := parseSince(sinceFlag, time.Now())
+		if err != nil {
+			return err
+		}
+		fnames = filterSince(cfg, fnames, since)
+		mlog.Printf("solutions downloaded since %s: %d", since.Format(time.RFC3339), len(fnames))
+	}
+	if perAuthorFlag > 0 {
+		var dropped int
+		fnames, dropped = capPerAuthor(fnames, perAuthorFlag)
+		mlog.Printf("solutions dropped by per author cap: %d", dropped)
+	}
+	if canonicalDedupFlag {
+		fnames = dedupCanonical(cfg, fnames)
+	}
+	var prev []*solutionStats
+	if appendFlag {
+		var savedAt time.Time
+		if prev, savedAt, err = loadResultsToAppend(cfg, saveFlag); err != nil {
+			return err
+		}
+		before := len(fnames)
+		fnames = filterUnsaved(cfg, fnames, prev, savedAt)
+		mlog.Printf("solutions saved in %s and unchanged: %d", saveFlag, before-len(fnames))
+		if len(fnames) == 0 {
+			mlog.Printf("no new or changed solutions to bench")
+			return nil
+		}
+	}
+	total := len(fnames)
+	if total == 0 {
 		return errors.New("found 0 solutions")
 	}
+	if baselineFlag != "" && !hasBaseline(fnames, prev) {
+		return fmt.Errorf("baseline solution %q not found", baselineFlag)
+	}
+	if shuffleFlag {
+		seed := seedFlag
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		rand.New(rand.NewSource(seed)).Shuffle(len(fnames), func(i, j int) {
+			fnames[i], fnames[j] = fnames[j], fnames[i]
+		})
+		mlog.Printf("solutions shuffled with seed %d", seed)
+	}
 	mlog.Printf("solutions total: %d", total)
 	mlog.Println()
 
-	wg := sync.WaitGroup{}
-	sstats := []*solutionStats{}
-	mx := sync.Mutex{}
+	// get toolchains
+	toolchains, err := getToolchains(ctx)
+	if err != nil {
+		return err
+	}
+	labels := toolchainLabels(toolchains)
 
-	// run all benches in test suite for all solutions
-	for _, fi := range fis {
-		if !regular(fi) {
-			continue
+	// create log dir
+	if logDirFlag != "" {
+		if err := os.MkdirAll(logDirFlag, 0700); err != nil {
+			return err
 		}
+	}
 
-		// enqueue bench task
-		wg.Add(1)
-		fname := fi.Name()
-
-		tq <- func() {
-			defer wg.Done()
+	// get code sizes in advance
+	spaths := []string{}
+	for _, fn := range fnames {
+		spaths = append(spaths, cfg.solutionsDir(fn))
+	}
+	var cache *sizeCache
+	if !noSizeCacheFlag {
+		if cache, err = loadSizeCache(filepath.Join(cfg.dir, "size-cache.json")); err != nil {
+			return err
+		}
+	}
+	sizes, serrs := getCodeSizes(spaths, cache)
+	if cache != nil {
+		if err := cache.save(); err != nil {
+			mlog.Printf("size cache save error: %v", err)
+		}
+	}
 
-			// create temp dir
-			tmp, err := ioutil.TempDir("", "")
-			if err != nil {
-				mlog.Printf("temp dir create error: %v", err)
-				return
-			}
-			defer os.RemoveAll(tmp)
+	// open results stream, results aren't kept in memory then
+	// low memory mode streams them to a temp file to read them back for the report
+	var (
+		stream     *resultsStream
+		lowMemPath string
+	)
+	if streamFlag != "" {
+		if stream, err = createResultsStream(cfg, streamFlag, currentEnv(toolchains)); err != nil {
+			return err
+		}
+		defer stream.close()
+	} else if lowMemFlag {
+		f, err := ioutil.TempFile("", "exercism-bench-*.jsonl")
+		if err != nil {
+			return err
+		}
+		lowMemPath = f.Name()
+		f.Close()
+		defer os.Remove(lowMemPath)
+		if stream, err = createResultsStream(cfg, lowMemPath, currentEnv(toolchains)); err != nil {
+			return err
+		}
+		defer stream.close()
+	}
 
-			// copy all required files to temp dir
-			dpath := filepath.Join(tmp, fname)
-			if err = copyFile(solutionsDir(fname), dpath); err != nil {
-				mlog.Printf("copy file error: %v", err)
-				return
+	startTime := time.Now()
+	wg := sync.WaitGroup{}
+	sstats := []*solutionStats{}
+	done := 0
+	mx := sync.Mutex{}
+	// fail fast mode cancels the context to skip remaining work
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var failErr error
+
+	// run all benches in test suite for all solutions, rounds interleave solutions
+	runs := total * roundsFlag
+	started := false
+	testFailures := 0
+	for round := 1; round <= roundsFlag && ctx.Err() == nil; round++ {
+		if roundsFlag > 1 {
+			vlogf("round %d / %d", round, roundsFlag)
+		}
+		for _, fn := range fnames {
+			if ctx.Err() != nil {
+				break
 			}
-			if err = copyFiles(solutionsDir("test-suite"), tmp); err != nil {
-				mlog.Printf("copy test suite files error: %v", err)
-				return
+			fname := fn
+			if err := serrs[cfg.solutionsDir(fname)]; err != nil {
+				if round == 1 {
+					mlog.Printf("bench of %s failed: %v", fname, err)
+				}
+				continue
 			}
+			size := sizes[cfg.solutionsDir(fname)]
+
+			// enqueue bench task
+			wg.Add(1)
+
+			tq <- func() {
+				defer wg.Done()
+				if ctx.Err() != nil {
+					return
+				}
+
+				// let CPU cool down after a previous bench
+				if cooldownFlag > 0 {
+					mx.Lock()
+					first := !started
+					started = true
+					mx.Unlock()
+					if !first && sleep(ctx, cooldownFlag) != nil {
+						return
+					}
+				}
+
+				// create build dir
+				tmp, err := makeBuildDir(cfg, tsDir, fname)
+				if err != nil {
+					mlog.Printf("build dir of %s failed: %v", fname, err)
+					return
+				}
+				defer os.RemoveAll(tmp)
+
+				// run bench with each toolchain
+				benched := false
+				for i, tc := range toolchains {
+					label := labels[i]
+					bstats, out, err := benchSolution(ctx, tc, label, tmp, fname)
+					_, partial := err.(*exercism.PartialError)
+					failed := exercism.FailedTests(out)
+					if len(failed) != 0 {
+						mx.Lock()
+						testFailures++
+						mx.Unlock()
+						mlog.Printf("tests of %s with %s failed: %s", fname, label, strings.Join(failed, ", "))
+					}
+					if partial {
+						mlog.Printf("bench of %s with %s is incomplete: %v", fname, label, err)
+					} else if err != nil {
+						if len(failed) == 0 {
+							mlog.Printf("bench of %s with %s failed: %v", fname, label, err)
+						}
+						if failFastFlag {
+							mx.Lock()
+							if failErr == nil {
+								failErr = fmt.Errorf("bench of %s with %s failed: %v\n%s", fname, label, err, out)
+							}
+							mx.Unlock()
+							cancel()
+							return
+						}
+						continue
+					}
+
+					if len(failed) != 0 && skipFailingFlag {
+						continue
+					}
+
+					// prepare stats
+					name, _, _ := parseSolutionFileName(fname)
+					if len(toolchains) > 1 {
+						name += " @" + label
+					}
+					st := &solutionStats{
+						file:      fname,
+						name:      name,
+						goVersion: tc.Version,
+						bstats:    bstats,
+						size:      size,
+						partial:   partial,
+						failed:    failed,
+					}
+					if stream != nil {
+						if err := stream.write(st); err != nil {
+							mlog.Printf("stream of %s results failed: %v", fname, err)
+						}
+					} else {
+						mx.Lock()
+						sstats = append(sstats, st)
+						mx.Unlock()
+					}
+					benched = true
+				}
+				if !benched {
+					return
+				}
 
-			// run bench
-			bstats, err := runBench(tmp, ".")
-			if err != nil {
-				mlog.Printf("bench of %s failed: %v", fname, err)
-				return
-			}
+				mx.Lock()
+				done++
+				count := done
+				crossed := progressStepCrossed(count, runs)
+				mx.Unlock()
 
-			// prepare stats
-			size, err := getCodeSize(dpath)
-			if err != nil {
-				mlog.Printf("bench of %s failed: %v", fname, err)
-				return
-			}
-			st := &solutionStats{
-				name:   fname,
-				bstats: bstats,
-				size:   size,
+				// report progress, items between progress steps are logged only in verbose mode
+				logf := vlogf
+				if crossed {
+					logf = mlog.Printf
+				}
+				logf("benched %-64s: %5d / %5d - %5.1f%%",
+					fname, count, runs, float32(count)/float32(runs)*100)
+				reportProgress("bench", fname, count, runs)
 			}
-
-			mx.Lock()
-			sstats = append(sstats, st)
-			count := len(sstats)
-			mx.Unlock()
-
-			// report progress
-			mlog.Printf("benched %-64s: %5d / %5d - %5.1f%%",
-				st.name, count, total, float32(count)/float32(total)*100)
 		}
+
+		// each round completes before the next one
+		wg.Wait()
 	}
 
 	// wait all tasks
 	wg.Wait()
+	if failErr != nil {
+		return failErr
+	}
+	if testFailures != 0 {
+		mlog.Println()
+		mlog.Printf("solution benches with failed tests: %d", testFailures)
+	}
+	if roundsFlag > 1 {
+		sstats = mergeRounds(sstats)
+	}
+
+	if streamFlag != "" {
+		mlog.Println()
+		mlog.Printf("results streamed to %s, use report command to sort them", streamFlag)
+		return nil
+	}
+
+	// save results merged with previous ones in append mode
+	merged := sstats
+	if appendFlag {
+		merged = mergeResults(prev, sstats)
+	}
+	if saveFlag != "" {
+		if err := saveResults(cfg, saveFlag, currentEnv(toolchains), merged); err != nil {
+			return err
+		}
+	}
+	if dbFlag != "" {
+		if err := writeResultsDB(cfg, dbFlag, sstats, startTime); err != nil {
+			return fmt.Errorf("write to %s failed: %v", dbFlag, err)
+		}
+	}
 
 	// print stats in sorted way
 	mlog.Println()
+	if n := benchMaxProcs(); n > 0 {
+		mlog.Printf("benchmarks GOMAXPROCS: %d", n)
+	} else {
+		mlog.Printf("benchmarks GOMAXPROCS: inherited")
+	}
+	mlog.Printf("benchmark runs: %d, reduced by %s", countFlag, reduceFlag)
+	mlog.Printf("host: %s/%s, CPUs: %d", runtime.GOOS, runtime.GOARCH, runtime.NumCPU())
+	for i, tc := range toolchains {
+		mlog.Printf("toolchain: %s %s (%s)", labels[i], tc.Platform, tc.Bin)
+	}
+	if baselineFlag != "" {
+		mlog.Printf("baseline: %s", baselineFlag)
+	}
+	mlog.Println()
+	if lowMemFlag {
+		if err := stream.close(); err != nil {
+			return err
+		}
+		return printFileReport(mlog, lowMemPath, bnames, total*len(toolchains))
+	}
+	// previous results kept in append mode are expected as well
+	printReport(mlog, merged, bnames, total*len(toolchains)+len(merged)-len(sstats))
+
+	// check regressions
+	if compareFileFlag != "" {
+		base, err := loadResults(compareFileFlag)
+		if err != nil {
+			return err
+		}
+		if n := printRegressions(mlog, base, sstats, bnames, regressFlag); n != 0 {
+			return fmt.Errorf("%d benchmarks regressed by more than %g%%", n, regressFlag)
+		}
+	}
+
+	return nil
+}
+
+func reportCmd(ctx context.Context, _ *config, _ chan<- task, args []string) error {
+	if len(args) != 1 {
+		return errInvalidUsage
+	}
+
+	sstats, err := loadResults(args[0])
+	if err != nil {
+		return err
+	}
+	env, err := loadResultsEnv(args[0])
+	if err != nil {
+		return err
+	}
+	printEnv(mlog, env)
+	printReport(mlog, sstats, benchNames(sstats), len(sstats))
+
+	return nil
+}
+
+func significanceCmd(ctx context.Context, _ *config, _ chan<- task, args []string) error {
+	if len(args) != 2 {
+		return errInvalidUsage
+	}
+
+	// load results
+	olds, err := loadResults(args[0])
+	if err != nil {
+		return err
+	}
+	news, err := loadResults(args[1])
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*solutionStats, len(olds))
+	for _, st := range olds {
+		byName[st.name] = st
+	}
+	sort.Slice(news, func(i, j int) bool { return news[i].name < news[j].name })
+
+	// compare paired solutions for each benchmark
+	bnames := benchNames(news)
+
+	mlog.Printf("Mann-Whitney U test, alpha: %g", alphaFlag)
+	mlog.Println()
 	for _, bn := range bnames {
 		mlog.Printf("------------------------------ %s ------------------------------", bn)
 		mlog.Println()
-		sortSolutionStatsByBench(sstats, bn)
-		for i, st := range sstats {
-			mlog.Printf("[%5d] %-64s: %s %15d symbols",
-				i+1, st.name, st.bstats[bn], st.size)
+		for _, nst := range news {
+			ost := byName[nst.name]
+			if ost == nil || ost.bstats[bn] == nil || nst.bstats[bn] == nil {
+				continue
+			}
+			ob, nb := ost.bstats[bn], nst.bstats[bn]
+			p := mannWhitneyU(ob.samples, nb.samples)
+			delta := "~"
+			if p < alphaFlag && ob.time != 0 {
+				delta = fmt.Sprintf("%+.2f%%", (nb.time-ob.time)/ob.time*100)
+			}
+			mlog.Printf("%-64s: %15.*f ns -> %15.*f ns %10s (p=%.3f n=%d+%d)",
+				nst.name, precisionFlag, ob.time, precisionFlag, nb.time, delta, p, len(ob.samples), len(nb.samples))
 		}
 		mlog.Println()
 	}
@@ -248,43 +1034,135 @@ func benchCmd(tq chan<- task, args []string) error {
 	return nil
 }
 
-func downloadCmd(tq chan<- task, args []string) error {
-	if len(args) != 0 {
+func topAuthorsCmd(ctx context.Context, cfg *config, _ chan<- task, args []string) error {
+	if len(args) > 1 || topFlag < 1 {
 		return errInvalidUsage
 	}
 
-	// get all paths
-	uuids, err := getSolutionUUIDs(tq)
+	// count solutions of each author
+	fnames, err := listSolutionFiles(cfg)
 	if err != nil {
 		return err
 	}
-	mlog.Printf("solutions total: %d", len(uuids))
-	mlog.Println()
+	authors := countAuthors(fnames)
+	if len(authors) == 0 {
+		return errors.New("found 0 solutions with authors")
+	}
+
+	// count top places in results
+	var sstats []*solutionStats
+	if len(args) == 1 {
+		if sstats, err = loadResults(args[0]); err != nil {
+			return err
+		}
+		countTopPlaces(authors, sstats, benchNames(sstats), topFlag)
+	}
+	printTopAuthors(mlog, authors, sstats != nil)
+
+	return nil
+}
+
+func debugPageCmd(ctx context.Context, cfg *config, _ chan<- task, args []string) error {
+	if len(args) > 2 {
+		return errInvalidUsage
+	}
+
+	// parse optional args
+	uuid, path := "", ""
+	if len(args) > 0 && uuidRE.MatchString(args[0]) {
+		uuid, args = args[0], args[1:]
+	}
+	if len(args) > 1 {
+		return errInvalidUsage
+	}
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	// get page as is
+	page, pageURL, err := getSolutionPage(ctx, cfg, uuid, nil)
+	if err != nil {
+		return fmt.Errorf("download of %s failed: %v", pageURL, err)
+	}
+	if path == "" {
+		_, err = io.WriteString(os.Stdout, page)
+		return err
+	}
+	if err = ioutil.WriteFile(path, []byte(page), 0600); err != nil {
+		return err
+	}
+	mlog.Printf("%s written to %s", pageURL, path)
+
+	return nil
+}
+
+func downloadCmd(ctx context.Context, cfg *config, tq chan<- task, args []string) error {
+	if len(args) != 0 {
+		return errInvalidUsage
+	}
+	// existing solutions are kept on a refresh of their test suite
+	if refreshSuiteFlag {
+		return suiteCmd(ctx, cfg, tq, args)
+	}
 
 	// download each solution
-	count := 0
+	total, count := 0, 0
 	mx := sync.Mutex{}
 
-	if err = getSolutionCodes(tq, uuids, func(uuid, author string) {
+	failed, unverified, err := getSolutionCodes(ctx, cfg, cfg.source, tq, func(n int) {
+		total = n
+		mlog.Printf("solutions total: %d", total)
+		mlog.Println()
+	}, func(uuid, author string) {
 		mx.Lock()
 		count++
 		c := count
+		crossed := progressStepCrossed(c, total)
 		mx.Unlock()
-		mlog.Printf("downloaded %s of %-32s: %5d / %5d - %5.1f%%",
-			uuid, author, c, len(uuids), float32(c)/float32(len(uuids))*100)
-	}); err != nil {
+		logf := vlogf
+		if crossed {
+			logf = mlog.Printf
+		}
+		logf("downloaded %s of %-32s: %5d / %5d - %5.1f%%",
+			uuid, author, c, total, float32(c)/float32(total)*100)
+		reportProgress("download", uuid, c, total)
+	})
+	if err != nil {
 		return err
 	}
 
+	// print summary
+	mlog.Println()
+	mlog.Printf("solutions total: %5d", total)
+	mlog.Printf("downloaded:      %5d", count)
+	mlog.Printf("failed:          %5d", failed)
+	if verifyDownloadFlag {
+		mlog.Printf("unverified:      %5d", unverified)
+	}
+
 	return nil
 }
 
-func cleanCmd(_ chan<- task, args []string) error {
+func suiteCmd(ctx context.Context, cfg *config, tq chan<- task, args []string) error {
 	if len(args) != 0 {
 		return errInvalidUsage
 	}
 
-	cp := solutionsDir()
+	// get test suite
+	if err := getTestSuite(ctx, cfg, cfg.source); err != nil {
+		return err
+	}
+	mlog.Printf("test suite downloaded to %s", cfg.solutionsDir("test-suite"))
+
+	return nil
+}
+
+func cleanCmd(ctx context.Context, cfg *config, _ chan<- task, args []string) error {
+	if len(args) != 0 {
+		return errInvalidUsage
+	}
+
+	cp := cfg.solutionsDir()
 	if err := os.RemoveAll(cp); err != nil {
 		return err
 	}
@@ -293,137 +1171,546 @@ func cleanCmd(_ chan<- task, args []string) error {
 	return nil
 }
 
-func solutionsDir(path ...string) string {
-	return filepath.Join(append([]string{downloadDirFlag, trackLang, exercise}, path...)...)
+func verifyCmd(ctx context.Context, cfg *config, _ chan<- task, args []string) error {
+	if len(args) != 0 {
+		return errInvalidUsage
+	}
+
+	// check test suite
+	tsErr := checkTestSuite(testSuiteDir(cfg))
+	if tsErr == nil {
+		tsErr = typeCheckTestSuite(testSuiteDir(cfg))
+	}
+	if tsErr != nil {
+		mlog.Printf("test suite is broken: %v", tsErr)
+	} else {
+		mlog.Printf("test suite is ok")
+	}
+	mlog.Println()
+
+	// check each solution
+	fnames, err := listSolutionFiles(cfg)
+	if err != nil {
+		return err
+	}
+	good, broken := 0, 0
+	fs := token.NewFileSet()
+
+	for _, fn := range fnames {
+		if _, err := parser.ParseFile(fs, cfg.solutionsDir(fn), nil, parser.AllErrors); err != nil {
+			broken++
+			mlog.Printf("broken %s: %v", fn, err)
+			continue
+		}
+		good++
+	}
+	if broken != 0 {
+		mlog.Println()
+	}
+	mlog.Printf("solutions good: %d, broken: %d", good, broken)
+
+	if tsErr != nil || broken != 0 {
+		return errors.New("verification failed")
+	}
+	return nil
+}
+
+// config is an exercise commands work with and a site to get it from.
+type config struct {
+	dir      string          // dir to store solutions of all exercises
+	exercise string          // exercise name, empty for track commands
+	addr     string          // site address
+	client   *http.Client    // client for site requests
+	source   exercism.Source // source of solutions of the exercise
+}
+
+// solutionsDir returns a path in the dir of exercise solutions.
+func (cfg *config) solutionsDir(path ...string) string {
+	return filepath.Join(append([]string{cfg.dir, trackLang, cfg.exercise}, path...)...)
+}
+
+// makeBuildDir creates a temp dir with a solution file, test suite and include files.
+// The caller removes the dir.
+func makeBuildDir(cfg *config, tsDir, fname string) (dir string, err error) {
+	// a nested solution dir is copied as a whole
+	solution := cfg.solutionsDir(fname)
+	if dir := filepath.Dir(fname); dir != "." {
+		solution = cfg.solutionsDir(dir)
+	}
+	return exercism.MakeBuildDir(solution, tsDir, includeFlag...)
+}
+
+// benchSolution runs benchmarks in a build dir with a given toolchain retrying failed runs.
+// Raw output of the last run is stored in the log dir if it's set, label tells apart logs of toolchains.
+func benchSolution(ctx context.Context, tc *exercism.Toolchain, label, buildDir, fname string) (bstats map[string]*benchStats, out string, err error) {
+	bstats, out, err = runBench(ctx, tc.Bin, buildDir, ".")
+	for r := 1; r <= benchRetriesFlag && err != nil && err != errNoBenchmarks && ctx.Err() == nil; r++ {
+		vlogf("bench of %s with %s failed: %v; retry %d / %d", fname, label, err, r, benchRetriesFlag)
+		if sleep(ctx, benchRetryDelay) != nil {
+			break
+		}
+		bstats, out, err = runBench(ctx, tc.Bin, buildDir, ".")
+	}
+
+	if logDirFlag != "" {
+		lname := fname
+		if len(goBinsFlag) > 1 {
+			lname += "-" + label
+		}
+		lp := filepath.Join(logDirFlag, sanitizeFileName(lname)+".log")
+		if err := ioutil.WriteFile(lp, []byte(out), 0600); err != nil {
+			mlog.Printf("write of log %s failed: %v", lp, err)
+		}
+	}
+	return bstats, out, err
+}
+
+// listSolutionFiles returns paths of all solution files relative to solutions dir.
+// Both layouts are supported: Go files in solutions dir and <name>/<name>.go files of nested dirs.
+// Test suite and other nested dirs are ignored.
+func listSolutionFiles(cfg *config) (names []string, err error) {
+	return exercism.ListSolutionFiles(cfg.solutionsDir())
+}
+
+// solutionPath returns a path to store a solution file with a given name in the layout set by flags.
+// Nested layout puts it in its own dir, which is created.
+func solutionPath(cfg *config, fname string) (string, error) {
+	if layoutFlag != "nested" {
+		return cfg.solutionsDir(fname), nil
+	}
+	dir := cfg.solutionsDir(strings.TrimSuffix(fname, ".go"))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fname), nil
+}
+
+// dedupCanonical groups solution files with the same canonical code and returns the first file of each group.
+// Groups of several files are logged. Files which can't be parsed are kept as is.
+func dedupCanonical(cfg *config, fnames []string) (reps []string) {
+	groups := make(map[string][]string)
+	order := []string{}
+
+	for _, fn := range fnames {
+		bs, err := ioutil.ReadFile(cfg.solutionsDir(fn))
+		key := ""
+		if err == nil {
+			key, err = canonicalCode(fn, bs)
+		}
+		if err != nil {
+			vlogf("canonical code of %s failed: %v", fn, err)
+			reps = append(reps, fn)
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], fn)
+	}
+
+	mlog.Printf("canonical groups: %d of %d solutions", len(order)+len(reps), len(fnames))
+	for _, key := range order {
+		g := groups[key]
+		reps = append(reps, g[0])
+		if len(g) > 1 {
+			mlog.Printf("- %s (%d identical): %s", g[0], len(g), strings.Join(g[1:], ", "))
+		}
+	}
+	mlog.Println()
+	return reps
+}
+
+// parseSince parses a duration before now or a time in RFC 3339 or date only format.
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("since %q is neither duration nor time", v)
+}
+
+// filterSince keeps solution files modified (downloaded) not before a given time.
+func filterSince(cfg *config, fnames []string, since time.Time) (kept []string) {
+	for _, fn := range fnames {
+		fi, err := os.Stat(cfg.solutionsDir(fn))
+		if err != nil {
+			mlog.Printf("stat of %s failed: %v", fn, err)
+			continue
+		}
+		if !fi.ModTime().Before(since) {
+			kept = append(kept, fn)
+		}
+	}
+	return kept
+}
+
+// capPerAuthor keeps at most n solution files per author in UUID order.
+// Files w/o author in their names are always kept.
+func capPerAuthor(fnames []string, n int) (kept []string, dropped int) {
+	sorted := append([]string(nil), fnames...)
+	sort.Strings(sorted)
+
+	counts := make(map[string]int)
+	for _, fn := range sorted {
+		_, _, author := parseSolutionFileName(fn)
+		if author != "" {
+			if counts[author] >= n {
+				dropped++
+				continue
+			}
+			counts[author]++
+		}
+		kept = append(kept, fn)
+	}
+	return kept, dropped
+}
+
+// matchSolution checks if a solution file is identified by a file name, a name w/o extension or a UUID.
+func matchSolution(fileName, id string) bool {
+	name, uuid, _ := parseSolutionFileName(fileName)
+	return id == fileName || id == name || (uuid != "" && id == uuid)
+}
+
+// hasBaseline checks if baseline solution is among solution files to bench or previous results kept in append mode.
+func hasBaseline(fnames []string, prev []*solutionStats) bool {
+	for _, fn := range fnames {
+		if matchSolution(fn, baselineFlag) {
+			return true
+		}
+	}
+	for _, st := range prev {
+		if matchSolution(st.file, baselineFlag) {
+			return true
+		}
+	}
+	return false
 }
 
-type uuidMap map[string]struct{}
+// defaultNameTemplate is a template of solution file names parsed by parseSolutionFileName.
+const defaultNameTemplate = "{{.UUID}}-{{.Author}}.go"
 
-func getSolutionUUIDs(tq chan<- task) (uuids uuidMap, err error) {
-	// get first solutions group page
-	firstGroupPage, solutionsURL, err := getSolutionPage("", nil)
+// nameTemplate is a parsed template of downloaded solution file names.
+var nameTemplate *template.Template
+
+// parseNameTemplate parses a template of solution file names and checks it gives distinct .go names.
+func parseNameTemplate(text string) (*template.Template, error) {
+	t, err := template.New("name").Parse(text)
 	if err != nil {
-		err = fmt.Errorf("download of %s failed: %v", solutionsURL, err)
-		return
+		return nil, fmt.Errorf("name template is invalid: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, uuid := range []string{strings.Repeat("0", 32), strings.Repeat("f", 32)} {
+		n, err := renderFileName(t, uuid, "author")
+		if err != nil {
+			return nil, fmt.Errorf("name template is invalid: %v", err)
+		}
+		if filepath.Ext(n) != ".go" || strings.HasSuffix(n, "_test.go") {
+			return nil, fmt.Errorf("name template gives %q, not a .go file name", n)
+		}
+		names[n] = true
+	}
+	if len(names) != 2 {
+		return nil, errors.New("name template gives the same name for different UUIDs")
 	}
+	return t, nil
+}
 
-	// get total of solutions pages
-	ms := solutionGroupsNumberRE.FindStringSubmatch(firstGroupPage)
-	if ms == nil {
-		err = errors.New("can't find solution groups number")
-		return
+// solutionFileName returns a sanitized file name of a solution rendered by the name template.
+func solutionFileName(uuid, author string) (string, error) {
+	return renderFileName(nameTemplate, uuid, author)
+}
+
+func renderFileName(t *template.Template, uuid, author string) (string, error) {
+	buf := strings.Builder{}
+	err := t.Execute(&buf, struct{ UUID, Author string }{uuid, author})
+	if err != nil {
+		return "", err
+	}
+	return sanitizeFileName(buf.String()), nil
+}
+
+var (
+	claimedNames   = make(map[string]string)
+	claimedNamesMx sync.Mutex
+)
+
+// claimName reserves a solution file name for a solution UUID, so solutions don't overwrite each other.
+func claimName(name, uuid string) error {
+	claimedNamesMx.Lock()
+	defer claimedNamesMx.Unlock()
+	if u, ok := claimedNames[name]; ok && u != uuid {
+		return fmt.Errorf("name %s is already used by solution %s", name, u)
+	}
+	claimedNames[name] = uuid
+	return nil
+}
+
+// parseSolutionFileName strips a dir and .go extension from a solution file path and
+// splits it into UUID and author if it has <uuid>-<author>.go layout.
+// uuid and author are empty for other layouts.
+func parseSolutionFileName(fileName string) (name, uuid, author string) {
+	name = strings.TrimSuffix(filepath.Base(fileName), ".go")
+	if ms := solutionFileNameRE.FindStringSubmatch(name); ms != nil {
+		return name, ms[1], ms[3]
+	}
+	return name, "", ""
+}
+
+// testSuiteDir returns a custom test suite dir if set or a downloaded one otherwise.
+func testSuiteDir(cfg *config) string {
+	if suiteDirFlag != "" {
+		return suiteDirFlag
 	}
-	total, err := strconv.ParseUint(ms[1], 10, 64)
+	return cfg.solutionsDir("test-suite")
+}
+
+// getExerciseSlugs returns sorted slugs of all exercises in the track.
+func getExerciseSlugs(ctx context.Context, cfg *config, tq chan<- task) (slugs []string, err error) {
+	// get first exercises page
+	firstPage, firstURL, err := getExercisesPage(ctx, cfg, nil)
 	if err != nil {
+		err = fmt.Errorf("download of %s failed: %v", firstURL, err)
 		return
 	}
 
-	// schedule downloads
+	// get total of exercises pages, all exercises can be on a single page
+	total := uint64(1)
+	if ms := exercisePagesNumberRE.FindStringSubmatch(firstPage); ms != nil {
+		if total, err = strconv.ParseUint(ms[1], 10, 64); err != nil {
+			return
+		}
+	}
+
+	// schedule downloads of other pages
 	wg := sync.WaitGroup{}
 	mx := sync.Mutex{}
-	uuids = make(uuidMap)
+	found := make(map[string]struct{})
+	addSlugs := func(page string) {
+		mx.Lock()
+		for _, ms := range exerciseSlugRE.FindAllStringSubmatch(page, -1) {
+			found[ms[1]] = struct{}{}
+		}
+		mx.Unlock()
+	}
+	addSlugs(firstPage)
 
-	for i := uint64(0); i < total; i++ {
+	for i := uint64(1); i < total; i++ {
 		n := i
 		wg.Add(1)
 
 		tq <- func() {
 			defer wg.Done()
+			if ctx.Err() != nil {
+				return
+			}
 
-			// get solution group page
-			groupPage, groupURL, err := getSolutionPage("", map[string]string{
+			page, pageURL, err := getExercisesPage(ctx, cfg, map[string]string{
 				"page": strconv.FormatUint(n+1, 10),
 			})
 			if err != nil {
-				mlog.Printf("download of %s failed: %v", groupURL, err)
+				mlog.Printf("download of %s failed: %v", pageURL, err)
 				return
 			}
-
-			// get solution UUIDs
-			mss := solutionPathRE.FindAllStringSubmatch(groupPage, -1)
-			if mss == nil {
-				mlog.Printf("can't find solution UUIDs in %s", groupURL)
-				return
-			}
-			for _, ms := range mss {
-				// ignore duplicates if they appear
-				mx.Lock()
-				uuids[ms[1]] = struct{}{}
-				mx.Unlock()
-			}
+			addSlugs(page)
 		}
 	}
 
 	// wait all tasks
 	wg.Wait()
 
-	return uuids, nil
+	if len(found) == 0 {
+		return nil, errors.New("can't find exercises")
+	}
+	for s := range found {
+		slugs = append(slugs, s)
+	}
+	sort.Strings(slugs)
+
+	return slugs, nil
 }
 
-func getSolutionCodes(tq chan<- task, uuids uuidMap, got func(uuid, author string)) error {
-	if err := os.MkdirAll(solutionsDir(), 0700); err != nil {
-		return err
+// exerciseNotFoundError returns an error with close exercise names if they can be found.
+func exerciseNotFoundError(ctx context.Context, cfg *config, tq chan<- task) error {
+	slugs, err := getExerciseSlugs(ctx, cfg, tq)
+	if err != nil {
+		return fmt.Errorf("exercise %q not found", cfg.exercise)
 	}
 
-	// get test suite
-	for uuid := range uuids {
-		solutionPage, solutionURL, err := getSolutionPage(uuid, nil)
-		if err != nil {
-			mlog.Printf("download of test suite %s failed: %v", solutionURL, err)
-			return err
+	matches := []string{}
+	for _, s := range slugs {
+		if strings.Contains(s, cfg.exercise) || strings.Contains(cfg.exercise, s) || editDistance(s, cfg.exercise) <= 2 {
+			matches = append(matches, s)
 		}
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("exercise %q not found", cfg.exercise)
+	}
+	return fmt.Errorf("exercise %q not found; did you mean %s?", cfg.exercise, strings.Join(matches, ", "))
+}
 
-		// store test suite
-		ts, err := extractTestSuite(solutionPage)
-		if err != nil {
-			return err
-		}
-		tsp := solutionsDir("test-suite")
-		_ = os.Mkdir(tsp, 0700)
-		for fn, fc := range ts {
-			fp := filepath.Join(tsp, fn)
-			if err := ioutil.WriteFile(fp, []byte(fc), 0600); err != nil {
-				mlog.Printf("write of test file %s failed: %v", fp, err)
+// editDistance returns Levenshtein distance between strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
 			}
 		}
-		break
+		prev, cur = cur, prev
 	}
+	return prev[len(b)]
+}
 
-	// schedule downloads and stores
-	wg := sync.WaitGroup{}
+// getTestSuite downloads test suite from a source and stores it.
+func getTestSuite(ctx context.Context, cfg *config, src exercism.Source) error {
+	ts, err := src.FetchTestSuite(ctx)
+	if err != nil {
+		return err
+	}
+	return saveTestSuite(ctx, cfg, ts)
+}
 
-	for k := range uuids {
-		uuid := k
-		wg.Add(1)
+// saveTestSuite stores test suite files and downloads their data files if requested.
+func saveTestSuite(ctx context.Context, cfg *config, ts map[string]string) error {
+	tsp := cfg.solutionsDir("test-suite")
+	if err := os.MkdirAll(tsp, 0700); err != nil {
+		return err
+	}
+	for fn, fc := range ts {
+		fp := filepath.Join(tsp, fn)
+		if err := writeFileAtomic(fp, []byte(fc), 0600); err != nil {
+			mlog.Printf("write of test file %s failed: %v", fp, err)
+		}
+	}
+	if dataURLFlag != "" {
+		getDataFiles(ctx, cfg, exercism.DataFileNames(ts))
+	}
+	return nil
+}
 
-		tq <- func() {
-			defer wg.Done()
+// getDataFiles downloads data files referenced by test suite and stores them by their paths in test suite dir.
+// Failed files are only logged, benchmarks using them fail later.
+func getDataFiles(ctx context.Context, cfg *config, names []string) {
+	for _, n := range names {
+		urlv := strings.Join([]string{strings.TrimSuffix(dataURLFlag, "/"), cfg.exercise, n}, "/")
+		content, _, err := getPage(ctx, cfg, solTimeoutFlag, urlv, nil)
+		if err != nil {
+			mlog.Printf("download of data file %s failed: %v", urlv, err)
+			continue
+		}
+		fp := cfg.solutionsDir("test-suite", filepath.FromSlash(n))
+		if err = os.MkdirAll(filepath.Dir(fp), 0700); err == nil {
+			err = writeFileAtomic(fp, []byte(content), 0600)
+		}
+		if err != nil {
+			mlog.Printf("write of data file %s failed: %v", fp, err)
+			continue
+		}
+		vlogf("data file %s downloaded", n)
+	}
+}
 
-			// get solution page
-			solutionPage, solutionURL, err := getSolutionPage(uuid, nil)
-			if err != nil {
-				mlog.Printf("download of %s failed: %v", solutionURL, err)
+// getSolutionCodes downloads and stores codes of solutions from a source with their test suite
+// if it's absent. listed is called with a number of solutions before their downloads
+// and got is called for each stored one.
+// failed is a number of solutions failed to be downloaded or stored,
+// unverified is a number of stored ones removed after failed verification in verify download mode.
+func getSolutionCodes(ctx context.Context, cfg *config, src exercism.Source, tq chan<- task,
+	listed func(total int), got func(uuid, author string)) (failed, unverified int, err error) {
+	recordSuiteHash := func() {
+		if err := saveSuiteHash(cfg); err != nil {
+			mlog.Printf("record of test suite hash failed: %v", err)
+		}
+	}
+	keepSuite := checkTestSuite(cfg.solutionsDir("test-suite")) == nil
+	var verify func(path string) error
+	if verifyDownloadFlag {
+		verify = verifySolutionFile
+	}
+	mx := sync.Mutex{}
+
+	_, err = exercism.Download(ctx, exercism.DownloadOptions{
+		Dir:       cfg.solutionsDir(),
+		Source:    src,
+		Go:        func(t func()) { tq <- t },
+		KeepSuite: keepSuite,
+		SaveSuite: func(ts map[string]string) error {
+			if err := saveTestSuite(ctx, cfg, ts); err != nil {
+				return err
+			}
+			// record test suite solutions are downloaded against
+			recordSuiteHash()
+			return nil
+		},
+		SaveSolution: func(uuid, author, code string) (string, error) {
+			return saveSolution(cfg, uuid, author, code)
+		},
+		Verify:  verify,
+		Retries: downloadVerifyRetries,
+		Logf:    vlogf,
+		Listed: func(uuids []string) {
+			listed(len(uuids))
+			if !keepSuite {
 				return
 			}
-
-			// extract solution code
-			code, author, err := extractSolutionCode(solutionPage)
-			if err != nil {
-				mlog.Printf("code extraction for %s failed: %v", solutionURL, err)
+			mlog.Printf("test suite exists, its download skipped")
+			if _, err := os.Stat(cfg.solutionsDir(suiteHashFileName)); os.IsNotExist(err) {
+				recordSuiteHash()
+			}
+		},
+		Done: func(uuid, author string, err error) {
+			if err == nil {
+				got(uuid, author)
 				return
 			}
-
-			// store solution code
-			fp := solutionsDir(uuid + "-" + author + ".go")
-			if err := ioutil.WriteFile(fp, []byte(code), 0600); err != nil {
-				mlog.Printf("write of %s failed: %v", fp, err)
+			mx.Lock()
+			defer mx.Unlock()
+			if verr, ok := err.(*exercism.VerifyError); ok {
+				mlog.Printf("%v; file removed", verr)
+				unverified++
+				return
 			}
-			got(uuid, author)
-		}
-	}
-
-	// wait all tasks
-	wg.Wait()
+			mlog.Print(err)
+			failed++
+		},
+	})
+	return failed, unverified, err
+}
 
-	return nil
+// saveSolution stores solution code under a unique name in the layout set by flags and returns its path.
+func saveSolution(cfg *config, uuid, author, code string) (path string, err error) {
+	fn, err := solutionFileName(uuid, author)
+	if err == nil {
+		err = claimName(fn, uuid)
+	}
+	if err != nil {
+		return "", fmt.Errorf("file name of %s failed: %v", uuid, err)
+	}
+	fp, err := solutionPath(cfg, fn)
+	if err != nil {
+		return "", fmt.Errorf("dir of %s failed: %v", fn, err)
+	}
+	if err = writeFileAtomic(fp, []byte(code), 0600); err != nil {
+		return "", fmt.Errorf("write of %s failed: %v", fp, err)
+	}
+	return fp, nil
 }
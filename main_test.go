@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseSolutionFileName(t *testing.T) {
+	cases := []struct {
+		file, name, uuid, author string
+	}{
+		{"0123456789abcdef0123456789ABCDEF-some-one.go", "0123456789abcdef0123456789ABCDEF-some-one", "0123456789abcdef0123456789ABCDEF", "some-one"},
+		{"dir/0123456789abcdef0123456789abcdef-x.go", "0123456789abcdef0123456789abcdef-x", "0123456789abcdef0123456789abcdef", "x"},
+		{"mine.go", "mine", "", ""},
+		{"my-fast-one.go", "my-fast-one", "", ""},
+		{"0123456789abcdef-short.go", "0123456789abcdef-short", "", ""},
+		{"0123456789abcdef0123456789abcdeg-bad.go", "0123456789abcdef0123456789abcdeg-bad", "", ""},
+		{"0123456789abcdef0123456789abcdef-.go", "0123456789abcdef0123456789abcdef-", "", ""},
+		{"no-ext", "no-ext", "", ""},
+	}
+	for _, c := range cases {
+		name, uuid, author := parseSolutionFileName(c.file)
+		if name != c.name || uuid != c.uuid || author != c.author {
+			t.Errorf("parseSolutionFileName(%q) = %q, %q, %q, want %q, %q, %q",
+				c.file, name, uuid, author, c.name, c.uuid, c.author)
+		}
+	}
+}
+
+// testConfig returns a config with solutions dir in a temp dir, which is created.
+func testConfig(t *testing.T) *config {
+	cfg := &config{dir: t.TempDir(), exercise: "ex"}
+	if err := os.MkdirAll(cfg.solutionsDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// writeTestFiles writes files with given paths relative to a dir creating their dirs.
+func writeTestFiles(t *testing.T, dir string, paths ...string) {
+	for _, p := range paths {
+		fp := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(fp), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fp, []byte("package ex\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListSolutionFilesStray(t *testing.T) {
+	cfg := testConfig(t)
+	writeTestFiles(t, cfg.solutionsDir(),
+		"a.go",
+		"b.go",
+		"nested/nested.go",
+		"test-suite/ex_test.go",
+		"test-suite/test-suite.go",
+		suiteHashFileName,
+		"results.json",
+		"notes.txt",
+		"empty/readme.md",
+		"odd/other.go",
+	)
+	if err := os.Mkdir(cfg.solutionsDir("dir.go"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	fnames, err := listSolutionFiles(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(fnames)
+	want := []string{"a.go", "b.go", filepath.Join("nested", "nested.go")}
+	if !reflect.DeepEqual(fnames, want) {
+		t.Errorf("solution files = %v, want %v", fnames, want)
+	}
+}
+
+// waitTimeout waits for a wait group and fails if it takes longer than a deadlock could be told from.
+func waitTimeout(t *testing.T, wg *sync.WaitGroup) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("tasks didn't complete, deadlock?")
+	}
+}
+
+func TestStartWorkersSelfScheduling(t *testing.T) {
+	tq, stop := startWorkers(1)
+	defer stop()
+
+	// each task of the tree enqueues its children while the only worker is busy with it
+	const fanout, depth = 4, 6
+	var done int64
+	wg := sync.WaitGroup{}
+	var schedule func(level int)
+	schedule = func(level int) {
+		wg.Add(1)
+		tq <- func() {
+			defer wg.Done()
+			atomic.AddInt64(&done, 1)
+			if level < depth {
+				for i := 0; i < fanout; i++ {
+					schedule(level + 1)
+				}
+			}
+		}
+	}
+
+	// many more tasks are enqueued from outside at the same time
+	const outside = 5000
+	for i := 0; i < outside; i++ {
+		wg.Add(1)
+		tq <- func() {
+			defer wg.Done()
+			atomic.AddInt64(&done, 1)
+		}
+		if i == outside/2 {
+			schedule(0)
+		}
+	}
+	waitTimeout(t, &wg)
+
+	// 1 + 4 + ... + 4^6 tasks in the tree
+	want := int64(outside)
+	for n, l := int64(1), 0; l <= depth; n, l = n*fanout, l+1 {
+		want += n
+	}
+	if done != want {
+		t.Errorf("done tasks = %d, want %d", done, want)
+	}
+}
+
+func TestRunEarlyCommandError(t *testing.T) {
+	defer func(v bool) { concurrencyFlag = v }(concurrencyFlag)
+	concurrencyFlag = true
+
+	// a command fails while its tasks still run and enqueue more tasks
+	errEarly := errors.New("early error")
+	var done int64
+	commands["early-error"] = func(_ context.Context, _ *config, tq chan<- task, _ []string) error {
+		for i := 0; i < 20; i++ {
+			tq <- func() {
+				time.Sleep(20 * time.Millisecond)
+				tq <- func() {
+					time.Sleep(20 * time.Millisecond)
+					atomic.AddInt64(&done, 1)
+				}
+			}
+		}
+		return errEarly
+	}
+	defer delete(commands, "early-error")
+
+	if err := run([]string{"ex", "early-error"}); err != errEarly {
+		t.Fatalf("run error = %v, want %v", err, errEarly)
+	}
+	// outstanding tasks are completed before the queue is closed
+	if done := atomic.LoadInt64(&done); done != 20 {
+		t.Errorf("done tasks = %d, want 20", done)
+	}
+}
+
+func TestExercisePageREs(t *testing.T) {
+	cases := []struct {
+		re    *regexp.Regexp
+		in    string
+		group string // first submatch, empty if no match is expected
+	}{
+		{uuidRE, "0123456789abcdef0123456789ABCDEF", "EF"},
+		{uuidRE, "0123456789abcdef0123456789abcdef0", ""},
+		{uuidRE, "0123456789abcdef0123456789abcdeg", ""},
+		{exerciseSlugRE, `<a href="/tracks/go/exercises/two-fer">`, "two-fer"},
+		{exerciseSlugRE, `<a href="/tracks/rust/exercises/two-fer">`, ""},
+		{exercisePagesNumberRE, `<a href="/tracks/go/exercises?page=7">Last</a>`, "7"},
+		{exercisePagesNumberRE, `<a href="/tracks/go/exercises?page=2">Next</a>`, ""},
+	}
+	for _, c := range cases {
+		var got string
+		if ms := c.re.FindStringSubmatch(c.in); ms != nil {
+			got = ms[1]
+		}
+		if got != c.group {
+			t.Errorf("%s on %q: got %q, want %q", c.re, c.in, got, c.group)
+		}
+	}
+}
+
+func TestRunSkipFailingWithoutTests(t *testing.T) {
+	defer func(v bool) { skipFailingFlag = v }(skipFailingFlag)
+	skipFailingFlag = true
+
+	// failed tests are known only when tests are run
+	if err := run([]string{"ex", "bench"}); err != errInvalidUsage {
+		t.Errorf("run error = %v, want %v", err, errInvalidUsage)
+	}
+}
+
+func TestHasBaseline(t *testing.T) {
+	defer func(v string) { baselineFlag = v }(baselineFlag)
+	fnames := []string{"0123456789abcdef0123456789abcdef-alice.go"}
+	prev := []*solutionStats{{file: "fedcba9876543210fedcba9876543210-bob.go"}}
+
+	cases := []struct {
+		baseline string
+		want     bool
+	}{
+		{"0123456789abcdef0123456789abcdef", true},
+		// solutions saved before are benched again only if they have changed
+		{"fedcba9876543210fedcba9876543210-bob", true},
+		{"fedcba9876543210fedcba9876543210", true},
+		{"11112222333344445555666677778888", false},
+	}
+	for _, c := range cases {
+		baselineFlag = c.baseline
+		if got := hasBaseline(fnames, prev); got != c.want {
+			t.Errorf("hasBaseline() of %q = %v, want %v", c.baseline, got, c.want)
+		}
+	}
+}
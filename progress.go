@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// progressEvent is a machine-readable progress line.
+type progressEvent struct {
+	Command string  `json:"command"`
+	Item    string  `json:"item"`
+	Current int     `json:"current"`
+	Total   int     `json:"total"`
+	Percent float64 `json:"percent"`
+}
+
+var (
+	progressSink io.WriteCloser // nil if progress events are disabled
+	progressMx   sync.Mutex
+)
+
+// openProgress opens a progress sink if it's requested by flags.
+func openProgress() error {
+	switch {
+	case progressFileFlag != "" && progressFDFlag >= 0:
+		return errors.New("only one of progress file and fd can be set")
+	case progressFileFlag != "":
+		f, err := os.Create(progressFileFlag)
+		if err != nil {
+			return err
+		}
+		progressSink = f
+	case progressFDFlag >= 0:
+		progressSink = os.NewFile(uintptr(progressFDFlag), "progress")
+	}
+	return nil
+}
+
+func closeProgress() {
+	if progressSink != nil {
+		progressSink.Close()
+	}
+}
+
+// progressStepCrossed reports whether current of total items crosses a progress step boundary.
+// It's always true for the last item or if progress step isn't set.
+func progressStepCrossed(current, total int) bool {
+	if progressStepFlag <= 0 || current >= total {
+		return true
+	}
+	step := func(c int) int {
+		return int(float64(c) / float64(total) * 100 / progressStepFlag)
+	}
+	return step(current) != step(current-1)
+}
+
+// reportProgress writes a JSON line with progress of a command if the sink is open.
+func reportProgress(cmd, item string, current, total int) {
+	if progressSink == nil {
+		return
+	}
+	bs, err := json.Marshal(&progressEvent{
+		Command: cmd,
+		Item:    item,
+		Current: current,
+		Total:   total,
+		Percent: float64(current) / float64(total) * 100,
+	})
+	if err != nil {
+		return
+	}
+
+	progressMx.Lock()
+	defer progressMx.Unlock()
+	if _, err = progressSink.Write(append(bs, '\n')); err != nil {
+		mlog.Printf("progress write error: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log"
+	"sort"
+)
+
+// printRegressions prints time deltas of new stats to base ones of solutions with the same names
+// and returns a number of benchmarks with time increased by more than threshold percents.
+// Solutions and benchmarks absent in base stats are reported but not treated as regressions.
+func printRegressions(lg *log.Logger, base, sstats []*solutionStats, bnames []string, threshold float64) (regressed int) {
+	byName := make(map[string]*solutionStats, len(base))
+	for _, st := range base {
+		byName[st.name] = st
+	}
+	sorted := append([]*solutionStats(nil), sstats...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+
+	lg.Printf("------------------------------ Regressions ------------------------------")
+	lg.Printf("compared with %s, threshold: %g%%", compareFileFlag, threshold)
+	lg.Println()
+	for _, st := range sorted {
+		bst := byName[st.name]
+		if bst == nil {
+			lg.Printf("%-64s: no baseline", st.name)
+			continue
+		}
+		for _, bn := range bnames {
+			ob, nb := bst.bstats[bn], st.bstats[bn]
+			switch {
+			case nb == nil:
+				continue
+			case ob == nil || ob.time == 0:
+				lg.Printf("%-64s %-32s: no baseline", st.name, bn)
+				continue
+			}
+			delta := (nb.time - ob.time) / ob.time * 100
+			mark := ""
+			if delta > threshold {
+				regressed++
+				mark = " " + colorize("REGRESSED", colorRed)
+			}
+			lg.Printf("%-64s %-32s: %15.*f ns -> %15.*f ns %+8.2f%%%s",
+				st.name, bn, precisionFlag, ob.time, precisionFlag, nb.time, delta, mark)
+		}
+	}
+	lg.Println()
+	return regressed
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// printEnv prints environment of loaded results followed by an empty line, nothing is printed if it's unknown.
+func printEnv(lg *log.Logger, env benchEnv) {
+	if env.GOOS == "" {
+		return
+	}
+	if env.GOMAXPROCS > 0 {
+		lg.Printf("benchmarks GOMAXPROCS: %d", env.GOMAXPROCS)
+	} else {
+		lg.Printf("benchmarks GOMAXPROCS: inherited")
+	}
+	lg.Printf("host: %s/%s, CPUs: %d", env.GOOS, env.GOARCH, env.NumCPU)
+	for _, v := range env.GoVersions {
+		lg.Printf("toolchain: %s", v)
+	}
+	lg.Println()
+}
+
+// printReport prints stats sorted for each benchmark and optional overall leaderboard to a given logger.
+// expected is a number of stats entries expected for each benchmark.
+func printReport(lg *log.Logger, sstats []*solutionStats, bnames []string, expected int) {
+	// stats are given, so there are no errors
+	printReports(lg, bnames, expected, func(string) ([]*solutionStats, error) { return sstats, nil }) //nolint:errcheck
+}
+
+// printFileReport works like printReport but reads stats of one benchmark at a time from results file.
+func printFileReport(lg *log.Logger, path string, bnames []string, expected int) error {
+	return printReports(lg, bnames, expected, func(bn string) ([]*solutionStats, error) {
+		return readResults(path, func(n string) bool { return n == bn })
+	})
+}
+
+// printReports prints reports of given benchmarks with stats got by statsOf,
+// then omitted benchmarks, overall and by solution leaderboards if requested.
+func printReports(lg *log.Logger, bnames []string, expected int, statsOf func(bn string) ([]*solutionStats, error)) error {
+	ranks := make(map[string][]int)
+	benchRanks := make(map[string]map[string]int, len(bnames))
+	shown, omitted := []string{}, []string{}
+	for _, bn := range bnames {
+		sstats, err := statsOf(bn)
+		if err != nil {
+			return err
+		}
+		br := printBenchReport(lg, sstats, bn, expected)
+		if br == nil {
+			omitted = append(omitted, bn)
+			continue
+		}
+		shown = append(shown, bn)
+		benchRanks[bn] = br
+		for n, r := range br {
+			ranks[n] = append(ranks[n], r)
+		}
+	}
+	printOmitted(lg, omitted)
+
+	// print overall leaderboard
+	if overallFlag {
+		printOverall(lg, ranks, len(shown))
+	}
+	if bySolutionFlag {
+		printBySolution(lg, benchRanks, shown)
+	}
+	return nil
+}
+
+// printBenchReport prints stats sorted for given benchmark.
+// ranks contains ranks of solutions with results by their names,
+// it's nil if the benchmark has no results and is omitted.
+func printBenchReport(lg *log.Logger, sstats []*solutionStats, bn string, expected int) (ranks map[string]int) {
+	var excluded, dropped int
+	if onlyWithMemFlag {
+		sstats, excluded = withMem(sstats, bn)
+	}
+	if dropSuspectFlag {
+		sstats, dropped = withoutSuspect(sstats, bn, minNsFlag)
+	}
+	reported := 0
+	for _, st := range sstats {
+		if st.bstats[bn] != nil {
+			reported++
+		}
+	}
+	if reported == 0 && !showEmptyFlag {
+		return nil
+	}
+	lg.Printf("------------------------------ %s ------------------------------", bn)
+	lg.Printf("%d/%d reported", reported, expected)
+	if onlyWithMemFlag {
+		lg.Printf("%d excluded w/o mem stats", excluded)
+	}
+	if dropSuspectFlag {
+		lg.Printf("%d dropped as suspect with time below %v ns", dropped, minNsFlag)
+	}
+	lg.Printf("sorted by %s", strings.Join(sortKeys(), ", "))
+	if allocRateFlag {
+		lg.Printf("alloc rate = mem / time (B/ns)")
+	}
+	lg.Println()
+	sortSolutionStatsByBench(sstats, bn, sortKeys())
+	flagHeavyAllocs(sstats, bn, maxAllocsFlag, allocsRatioFlag)
+	flagFlaky(sstats, bn, flakyFlag)
+	flagFailedTests(sstats, bn)
+	flagSuspect(sstats, bn, minNsFlag)
+	var base *benchStats
+	for _, st := range sstats {
+		if baselineFlag != "" && matchSolution(st.file, baselineFlag) {
+			base = st.bstats[bn]
+			break
+		}
+	}
+	var pcts []float64
+	if percentileFlag {
+		pcts = fasterPercents(sstats, bn, reported)
+	}
+	ranks = make(map[string]int, reported)
+	missing := []string{}
+	for i, st := range sstats {
+		bst := st.bstats[bn]
+		if bst == nil {
+			n := st.name
+			if st.partial {
+				n += " (partial run)"
+			}
+			missing = append(missing, n)
+			continue
+		}
+		ranks[st.name] = i + 1
+		// filter by time keeping original ranks
+		if (minTimeFlag > 0 && bst.time < minTimeFlag) || (maxTimeFlag > 0 && bst.time > maxTimeFlag) {
+			continue
+		}
+		extra := ""
+		if pcts != nil {
+			extra = fmt.Sprintf(" faster than %5.1f%%", pcts[i])
+		}
+		if allocRateFlag {
+			if r := bst.allocRate(); r != -1 {
+				extra += fmt.Sprintf(" %12.3f B/ns", r)
+			} else {
+				extra += fmt.Sprintf(" %12s B/ns", "-")
+			}
+		}
+		lg.Printf("[%5d] %-64s: %s %15d %s%s%s%s",
+			i+1, st.name, bst, st.size, sizeMetricFlag, extra, bst.ratioString(base), bst.tagsString())
+		if showSamplesFlag && len(bst.samples) != 0 {
+			lg.Printf("%8s samples: %s ns", "", bst.samplesString())
+		}
+	}
+	if len(missing) != 0 {
+		lg.Println()
+		lg.Printf("no result:")
+		for _, n := range missing {
+			lg.Printf("- %s", n)
+		}
+	}
+	lg.Println()
+	return ranks
+}
+
+// withMem returns stats w/o solutions with results of a benchmark lacking mem stats and their number.
+func withMem(sstats []*solutionStats, bn string) (kept []*solutionStats, excluded int) {
+	for _, st := range sstats {
+		if bst := st.bstats[bn]; bst != nil && bst.mem == -1 {
+			excluded++
+			continue
+		}
+		kept = append(kept, st)
+	}
+	return kept, excluded
+}
+
+// withoutSuspect returns stats w/o solutions with time of a benchmark below minNs and their number.
+func withoutSuspect(sstats []*solutionStats, bn string, minNs float64) (kept []*solutionStats, dropped int) {
+	for _, st := range sstats {
+		if bst := st.bstats[bn]; bst != nil && bst.time < minNs {
+			dropped++
+			continue
+		}
+		kept = append(kept, st)
+	}
+	return kept, dropped
+}
+
+// printOmitted prints names of benchmarks omitted for lack of results.
+func printOmitted(lg *log.Logger, omitted []string) {
+	if len(omitted) == 0 {
+		return
+	}
+	lg.Printf("omitted benchmarks w/o results (see -show-empty flag): %s", strings.Join(omitted, ", "))
+	lg.Println()
+}
+
+// fasterPercents returns percents of other reported solutions with greater time for the first reported stats
+// sorted by a given benchmark, so solutions with equal time get the same percent.
+func fasterPercents(sstats []*solutionStats, bn string, reported int) []float64 {
+	pcts := make([]float64, reported)
+	if reported < 2 {
+		return pcts
+	}
+	for i := 0; i < reported; {
+		// find a group of equal time
+		j := i + 1
+		for j < reported && sstats[j].bstats[bn].time == sstats[i].bstats[bn].time {
+			j++
+		}
+		for k := i; k < j; k++ {
+			pcts[k] = float64(reported-j) / float64(reported-1) * 100
+		}
+		i = j
+	}
+	return pcts
+}
+
+// printOverall prints solutions sorted by average rank across benchmarks they have results for.
+// Solutions with results for more benchmarks go first among ones with equal average ranks.
+func printOverall(lg *log.Logger, ranks map[string][]int, benchs int) {
+	avg := func(n string) float64 {
+		sum := 0
+		for _, r := range ranks[n] {
+			sum += r
+		}
+		return float64(sum) / float64(len(ranks[n]))
+	}
+	ranked := []string{}
+	for n := range ranks {
+		ranked = append(ranked, n)
+	}
+	sort.Strings(ranked)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		ai, aj := avg(ranked[i]), avg(ranked[j])
+		return ai < aj || (ai == aj && len(ranks[ranked[i]]) > len(ranks[ranked[j]]))
+	})
+
+	lg.Printf("------------------------------ Overall ------------------------------")
+	lg.Printf("sorted by average rank")
+	lg.Println()
+	for i, n := range ranked {
+		lg.Printf("[%5d] %-64s: %10.2f avg rank %5d / %d benchmarks",
+			i+1, n, avg(n), len(ranks[n]), benchs)
+	}
+	lg.Println()
+}
+
+// printBySolution prints ranks of each solution within the field of each benchmark
+// marking the benchmark with the worst rank relative to the field size.
+// benchRanks contains ranks of solutions by their names for each benchmark.
+func printBySolution(lg *log.Logger, benchRanks map[string]map[string]int, bnames []string) {
+	names := []string{}
+	for _, bn := range bnames {
+		for n := range benchRanks[bn] {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	lg.Printf("------------------------------ By Solution ------------------------------")
+	lg.Printf("rank within field of each benchmark, the worst is marked")
+	lg.Println()
+	for i, n := range names {
+		if i > 0 && names[i-1] == n {
+			continue
+		}
+		// find the worst relative rank
+		worst, worstPos := "", -1.0
+		for _, bn := range bnames {
+			if r, ok := benchRanks[bn][n]; ok {
+				if pos := float64(r) / float64(len(benchRanks[bn])); pos > worstPos {
+					worst, worstPos = bn, pos
+				}
+			}
+		}
+
+		lg.Printf("%s:", n)
+		for _, bn := range bnames {
+			r, ok := benchRanks[bn][n]
+			if !ok {
+				lg.Printf("  %-40s: no result", bn)
+				continue
+			}
+			mark := ""
+			if bn == worst && len(benchRanks) > 1 {
+				mark = " " + colorize("WORST", colorYellow)
+			}
+			lg.Printf("  %-40s: %5d / %5d %6.1f%%%s",
+				bn, r, len(benchRanks[bn]), float64(r)/float64(len(benchRanks[bn]))*100, mark)
+		}
+	}
+	lg.Println()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"testing"
+)
+
+var updateFlag = flag.Bool("update", false, "update golden files of tests")
+
+// checkGolden compares output with a golden file in testdata or updates the file in update mode.
+func checkGolden(t *testing.T, name string, out []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *updateFlag {
+		if err := ioutil.WriteFile(path, out, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run with -update to create it", err)
+	}
+	if !bytes.Equal(out, want) {
+		t.Errorf("output differs from %s:\n%s\nwant:\n%s", path, out, want)
+	}
+}
+
+// reportStats returns stats of solutions for a report.
+// alice has all stats, bob has no throughput, carol has neither throughput nor mem,
+// dave has no BenchmarkB result and eve has no results of a partial run.
+func reportStats() []*solutionStats {
+	return []*solutionStats{
+		{file: "alice.go", name: "alice", size: 120, bstats: map[string]*benchStats{
+			"BenchmarkA": {time: 25.5, throughput: 150.25, mem: 16, allocs: 1},
+			"BenchmarkB": {time: 1200, throughput: 12.5, mem: 512, allocs: 4},
+		}},
+		{file: "bob.go", name: "bob", size: 95, bstats: map[string]*benchStats{
+			"BenchmarkA": {time: 18.25, throughput: -1, mem: 0, allocs: 0},
+			"BenchmarkB": {time: 1500, throughput: -1, mem: 256, allocs: 2},
+		}},
+		{file: "carol.go", name: "carol", size: 140, bstats: map[string]*benchStats{
+			"BenchmarkA": {time: 40, throughput: -1, mem: -1, allocs: -1},
+			"BenchmarkB": {time: 900, throughput: -1, mem: -1, allocs: -1},
+		}},
+		{file: "dave.go", name: "dave", size: 80, bstats: map[string]*benchStats{
+			"BenchmarkA": {time: 18.25, throughput: -1, mem: 8, allocs: 1},
+		}},
+		{file: "eve.go", name: "eve", size: 70, partial: true, bstats: map[string]*benchStats{}},
+	}
+}
+
+func TestPrintReportGolden(t *testing.T) {
+	defer func(ce, ov, bs bool) { colorEnabled, overallFlag, bySolutionFlag = ce, ov, bs }(colorEnabled, overallFlag, bySolutionFlag)
+	colorEnabled = false
+
+	cases := []struct {
+		name                string
+		overall, bySolution bool
+		bnames              []string
+	}{
+		{"report", false, false, []string{"BenchmarkA", "BenchmarkB"}},
+		{"report_overall", true, true, []string{"BenchmarkA", "BenchmarkB", "BenchmarkC"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			overallFlag, bySolutionFlag = c.overall, c.bySolution
+			buf := &bytes.Buffer{}
+			sstats := reportStats()
+			printReport(log.New(buf, "", 0), sstats, c.bnames, len(sstats))
+			checkGolden(t, c.name, buf.Bytes())
+		})
+	}
+}
+
+func TestPrintBenchReportGolden(t *testing.T) {
+	defer func(v bool) { colorEnabled = v }(colorEnabled)
+	colorEnabled = false
+
+	// a benchmark w/o throughput and mem stats of any solution
+	sstats := reportStats()
+	for _, st := range sstats {
+		if bst := st.bstats["BenchmarkA"]; bst != nil {
+			bst.throughput, bst.mem, bst.allocs = -1, -1, -1
+		}
+	}
+	buf := &bytes.Buffer{}
+	printBenchReport(log.New(buf, "", 0), sstats, "BenchmarkA", len(sstats))
+	checkGolden(t, "bench_report_no_throughput_mem", buf.Bytes())
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/avegner/exercism-bench/exercism"
+)
+
+// resultsVersion is a version of saved results format.
+const resultsVersion = 1
+
+type savedResults struct {
+	Version  int    `json:"version"`
+	Exercise string `json:"exercise"`
+	benchEnv
+	Solutions []*exercism.SolutionStats `json:"solutions,omitempty"`
+}
+
+// benchEnv describes a host and toolchains results are benched on, results are comparable only with the same one.
+type benchEnv struct {
+	GOOS       string   `json:"goos,omitempty"`
+	GOARCH     string   `json:"goarch,omitempty"`
+	NumCPU     int      `json:"num_cpu,omitempty"`
+	GOMAXPROCS int      `json:"gomaxprocs,omitempty"` // of benchmarks, 0 if it's inherited
+	GoVersions []string `json:"go_versions,omitempty"`
+}
+
+// currentEnv returns environment of benchmarks run by given toolchains on this host.
+func currentEnv(toolchains []*exercism.Toolchain) benchEnv {
+	env := benchEnv{
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: benchMaxProcs(),
+	}
+	for _, tc := range toolchains {
+		env.GoVersions = append(env.GoVersions, tc.Version)
+	}
+	return env
+}
+
+// saveResults stores solution stats with their environment to a JSON file.
+func saveResults(cfg *config, path string, env benchEnv, sstats []*solutionStats) error {
+	res := &savedResults{
+		Version:  resultsVersion,
+		Exercise: cfg.exercise,
+		benchEnv: env,
+	}
+	for _, st := range sstats {
+		res.Solutions = append(res.Solutions, toSavedSolution(st))
+	}
+
+	bs, err := json.MarshalIndent(res, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, bs, 0600)
+}
+
+// loadResultsToAppend loads saved results of the exercise to merge new ones into and their modification time.
+// A missing file has no results.
+func loadResultsToAppend(cfg *config, path string) (sstats []*solutionStats, mtime time.Time, err error) {
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// only saved results of the same exercise can be merged
+	res := &savedResults{}
+	if err = json.Unmarshal(bs, res); err != nil {
+		return nil, time.Time{}, fmt.Errorf("results %s are invalid: %v", path, err)
+	}
+	if res.Version != resultsVersion {
+		return nil, time.Time{}, fmt.Errorf("results %s have unsupported version %d", path, res.Version)
+	}
+	if res.Exercise != cfg.exercise {
+		return nil, time.Time{}, fmt.Errorf("results %s are of exercise %q", path, res.Exercise)
+	}
+	for _, ss := range res.Solutions {
+		sstats = append(sstats, fromSavedSolution(ss))
+	}
+	return sstats, fi.ModTime(), nil
+}
+
+// filterUnsaved keeps solution files absent in saved stats or modified after they have been saved.
+func filterUnsaved(cfg *config, fnames []string, saved []*solutionStats, savedAt time.Time) (kept []string) {
+	files := make(map[string]bool, len(saved))
+	for _, st := range saved {
+		files[st.file] = true
+	}
+	for _, fn := range fnames {
+		if files[fn] {
+			fi, err := os.Stat(cfg.solutionsDir(fn))
+			if err == nil && !fi.ModTime().After(savedAt) {
+				continue
+			}
+		}
+		kept = append(kept, fn)
+	}
+	return kept
+}
+
+// mergeResults replaces old stats of solutions with the same names by new ones and appends the rest of new ones.
+func mergeResults(olds, news []*solutionStats) (merged []*solutionStats) {
+	byName := make(map[string]*solutionStats, len(news))
+	for _, st := range news {
+		byName[st.name] = st
+	}
+	for _, st := range olds {
+		if _, ok := byName[st.name]; !ok {
+			merged = append(merged, st)
+		}
+	}
+	return append(merged, news...)
+}
+
+// loadResults loads solution stats from a JSON file of saved or streamed results.
+func loadResults(path string) (sstats []*solutionStats, err error) {
+	return readResults(path, nil)
+}
+
+// loadResultsEnv loads environment of saved or streamed results, it's empty for ones saved before it's recorded.
+func loadResultsEnv(path string) (env benchEnv, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return benchEnv{}, err
+	}
+	defer f.Close()
+
+	// environment is in the first object of both formats
+	res := &savedResults{}
+	if err = json.NewDecoder(f).Decode(res); err != nil {
+		return benchEnv{}, fmt.Errorf("results %s are invalid: %v", path, err)
+	}
+	return res.benchEnv, nil
+}
+
+// readResults loads solution stats from a JSON file of saved or streamed results.
+// Only benchmarks accepted by keep are loaded if it's not nil.
+func readResults(path string, keep func(bn string) bool) (sstats []*solutionStats, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// saved results are a single object, streamed ones are a header followed by solution objects
+	dec := json.NewDecoder(f)
+	res := &savedResults{}
+	if err = dec.Decode(res); err != nil {
+		return nil, fmt.Errorf("results %s are invalid: %v", path, err)
+	}
+	if res.Version != resultsVersion {
+		return nil, fmt.Errorf("results %s have unsupported version %d", path, res.Version)
+	}
+	for {
+		ss := &exercism.SolutionStats{}
+		if err = dec.Decode(ss); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("results %s are invalid: %v", path, err)
+		}
+		res.Solutions = append(res.Solutions, ss)
+	}
+
+	for _, ss := range res.Solutions {
+		if keep != nil {
+			for bn := range ss.Benchmarks {
+				if !keep(bn) {
+					delete(ss.Benchmarks, bn)
+				}
+			}
+		}
+		sstats = append(sstats, fromSavedSolution(ss))
+	}
+	return sstats, nil
+}
+
+// resultsStream writes solution stats to a file as JSON lines as soon as they are ready.
+type resultsStream struct {
+	mx  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// createResultsStream creates a stream file and writes a header line with environment to it.
+func createResultsStream(cfg *config, path string, env benchEnv) (*resultsStream, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	rs := &resultsStream{
+		f:   f,
+		enc: json.NewEncoder(f),
+	}
+	if err = rs.enc.Encode(&savedResults{
+		Version:  resultsVersion,
+		Exercise: cfg.exercise,
+		benchEnv: env,
+	}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return rs, nil
+}
+
+func (rs *resultsStream) write(st *solutionStats) error {
+	rs.mx.Lock()
+	defer rs.mx.Unlock()
+	return rs.enc.Encode(toSavedSolution(st))
+}
+
+func (rs *resultsStream) close() error {
+	return rs.f.Close()
+}
+
+func toSavedSolution(st *solutionStats) *exercism.SolutionStats {
+	ss := &exercism.SolutionStats{
+		File:       st.file,
+		Name:       st.name,
+		GoVersion:  st.goVersion,
+		Size:       st.size,
+		Benchmarks: make(map[string]*exercism.BenchStats, len(st.bstats)),
+		Partial:    st.partial,
+		Failed:     st.failed,
+	}
+	for bn, bst := range st.bstats {
+		ss.Benchmarks[bn] = toLibBenchStats(bst)
+	}
+	return ss
+}
+
+func fromSavedSolution(ss *exercism.SolutionStats) *solutionStats {
+	st := &solutionStats{
+		file:      ss.File,
+		name:      ss.Name,
+		goVersion: ss.GoVersion,
+		size:      ss.Size,
+		bstats:    make(map[string]*benchStats, len(ss.Benchmarks)),
+		partial:   ss.Partial,
+		failed:    ss.Failed,
+	}
+	for bn, sb := range ss.Benchmarks {
+		st.bstats[bn] = fromLibBenchStats(sb)
+	}
+	return st
+}
+
+func toLibBenchStats(bst *benchStats) *exercism.BenchStats {
+	return &exercism.BenchStats{
+		Time:       bst.time,
+		Throughput: bst.throughput,
+		Mem:        bst.mem,
+		Allocs:     bst.allocs,
+		Samples:    bst.samples,
+	}
+}
+
+func fromLibBenchStats(sb *exercism.BenchStats) *benchStats {
+	return &benchStats{
+		time:       sb.Time,
+		throughput: sb.Throughput,
+		mem:        sb.Mem,
+		allocs:     sb.Allocs,
+		samples:    sb.Samples,
+	}
+}
+
+// benchNames returns sorted names of all benchmarks found in stats.
+func benchNames(sstats []*solutionStats) (names []string) {
+	seen := make(map[string]struct{})
+	for _, st := range sstats {
+		for bn := range st.bstats {
+			if _, ok := seen[bn]; !ok {
+				seen[bn] = struct{}{}
+				names = append(names, bn)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/avegner/exercism-bench/exercism"
+)
+
+func TestSaveResultsRoundTrip(t *testing.T) {
+	defer func(v int) { benchMaxProcsFlag = v }(benchMaxProcsFlag)
+	benchMaxProcsFlag = 2
+	cfg := testConfig(t)
+	path := filepath.Join(cfg.dir, "results.json")
+	env := currentEnv([]*exercism.Toolchain{{Bin: "go", Version: "go1.20"}, {Bin: "go1.21", Version: "go1.21"}})
+
+	if err := saveResults(cfg, path, env, reportStats()); err != nil {
+		t.Fatal(err)
+	}
+	sstats, err := loadResults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(sstats); !reflect.DeepEqual(got, names(reportStats())) {
+		t.Errorf("loaded solutions = %v, want %v", got, names(reportStats()))
+	}
+	if st := sstats[0].bstats["BenchmarkA"]; st == nil || st.time != 25.5 || st.throughput != 150.25 {
+		t.Errorf("loaded stats = %+v, want time 25.5 and throughput 150.25", st)
+	}
+
+	// environment is saved along with results
+	got, err := loadResultsEnv(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, env) {
+		t.Errorf("loaded env = %+v, want %+v", got, env)
+	}
+	if env.GOOS == "" || env.GOARCH == "" || env.NumCPU == 0 || env.GOMAXPROCS != 2 ||
+		!reflect.DeepEqual(env.GoVersions, []string{"go1.20", "go1.21"}) {
+		t.Errorf("env = %+v, want host, 2 GOMAXPROCS and both toolchain versions", env)
+	}
+}
+
+func TestResultsStreamEnv(t *testing.T) {
+	cfg := testConfig(t)
+	path := filepath.Join(cfg.dir, "results.jsonl")
+	env := currentEnv(nil)
+
+	rs, err := createResultsStream(cfg, path, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range reportStats() {
+		if err := rs.write(st); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rs.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if sstats, err := loadResults(path); err != nil || len(sstats) != len(reportStats()) {
+		t.Errorf("loaded %d solutions, %v, want %d", len(sstats), err, len(reportStats()))
+	}
+	if got, err := loadResultsEnv(path); err != nil || !reflect.DeepEqual(got, env) {
+		t.Errorf("loaded env = %+v, %v, want %+v", got, err, env)
+	}
+}
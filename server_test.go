@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// sitePages are pages of a fake site: <exercise>-<page>.html group pages of single and multi exercises
+// and solutions/<uuid>.html solution pages.
+//
+//go:embed testdata/site
+var sitePages embed.FS
+
+// Solutions of exercises of the fake site. The last multi one has no page.
+var (
+	singleUUIDs = []string{
+		"0123456789abcdef0123456789abcdef",
+		"fedcba9876543210fedcba9876543210",
+	}
+	multiUUIDs = []string{
+		"0000ffff0000ffff0000ffff0000ffff",
+		"0123456789abcdef0123456789abcdef",
+		"11112222333344445555666677778888",
+		"99998888777766665555444433332222",
+		"aaaabbbbccccddddeeeeffff00001111",
+		"fedcba9876543210fedcba9876543210",
+	}
+	siteAuthors = map[string]string{
+		"0123456789abcdef0123456789abcdef": "alice",
+		"fedcba9876543210fedcba9876543210": "bob",
+		"11112222333344445555666677778888": "carol",
+		"aaaabbbbccccddddeeeeffff00001111": "dave-d",
+		"99998888777766665555444433332222": "eve",
+	}
+)
+
+// newTestServer starts a fake exercism site serving embedded pages.
+func newTestServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// paths are /tracks/go/exercises/<exercise>/solutions[/<uuid>]
+		ps := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(ps) < 5 || ps[0] != "tracks" || ps[1] != trackLang || ps[2] != "exercises" || ps[4] != "solutions" {
+			http.NotFound(w, r)
+			return
+		}
+		name := ps[3] + "-1.html"
+		if p := r.URL.Query().Get("page"); p != "" {
+			name = ps[3] + "-" + p + ".html"
+		}
+		if len(ps) == 6 {
+			name = "solutions/" + ps[5] + ".html"
+		}
+		bs, err := sitePages.ReadFile(path.Join("testdata/site", name))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(bs) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testSiteConfig returns a config to get solutions of an exercise from a fake site to a temp dir
+// with the site pages source. Solution file names have the default template.
+func testSiteConfig(t *testing.T, srv *httptest.Server, exercise string) *config {
+	useDefaultNameTemplate(t)
+	cfg := &config{
+		dir:      t.TempDir(),
+		exercise: exercise,
+		addr:     srv.URL,
+		client:   srv.Client(),
+	}
+	cfg.source = newHTMLSource(cfg, testWorkers(t, 2))
+	return cfg
+}
+
+// useDefaultNameTemplate sets the default template of solution file names till the end of a test.
+func useDefaultNameTemplate(t *testing.T) {
+	tmpl, err := parseNameTemplate(nameTemplateFlag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := nameTemplate
+	t.Cleanup(func() { nameTemplate = prev })
+	nameTemplate = tmpl
+}
+
+// testWorkers starts a pool of workers stopped at the end of a test.
+func testWorkers(t *testing.T, n int) chan<- task {
+	tq, stop := startWorkers(n)
+	t.Cleanup(stop)
+	return tq
+}
+
+func TestHTMLSourceListUUIDs(t *testing.T) {
+	srv := newTestServer(t)
+	cases := []struct {
+		exercise string
+		uuids    []string
+	}{
+		{"single", singleUUIDs},
+		// a solution on two pages is listed once
+		{"multi", multiUUIDs},
+	}
+	for _, c := range cases {
+		cfg := testSiteConfig(t, srv, c.exercise)
+		uuids, err := cfg.source.ListUUIDs(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", c.exercise, err)
+		}
+		if !reflect.DeepEqual(uuids, c.uuids) {
+			t.Errorf("%s: UUIDs = %v, want %v", c.exercise, uuids, c.uuids)
+		}
+	}
+}
+
+func TestGetSolutionCodesFromSite(t *testing.T) {
+	srv := newTestServer(t)
+	for _, exercise := range []string{"single", "multi"} {
+		cfg := testSiteConfig(t, srv, exercise)
+		tq := testWorkers(t, 3)
+
+		total := 0
+		got := make(map[string]string)
+		mx := sync.Mutex{}
+		failed, unverified, err := getSolutionCodes(context.Background(), cfg, cfg.source, tq,
+			func(n int) { total = n },
+			func(uuid, author string) {
+				mx.Lock()
+				got[uuid] = author
+				mx.Unlock()
+			})
+		if err != nil {
+			t.Fatalf("%s: %v", exercise, err)
+		}
+
+		// all solutions with pages are stored under names with their authors
+		uuids := singleUUIDs
+		if exercise == "multi" {
+			uuids = multiUUIDs
+		}
+		if total != len(uuids) || failed != len(uuids)-len(got) || unverified != 0 {
+			t.Errorf("%s: total, failed and unverified = %d, %d and %d, want %d, %d and 0",
+				exercise, total, failed, unverified, len(uuids), len(uuids)-len(got))
+		}
+		want := []string{}
+		for _, uuid := range uuids {
+			author, ok := siteAuthors[uuid]
+			if !ok {
+				continue
+			}
+			if got[uuid] != author {
+				t.Errorf("%s: author of %s = %q, want %q", exercise, uuid, got[uuid], author)
+			}
+			want = append(want, uuid+"-"+author+".go")
+		}
+		fnames, err := listSolutionFiles(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(fnames)
+		if !reflect.DeepEqual(fnames, want) {
+			t.Errorf("%s: stored solutions = %v, want %v", exercise, fnames, want)
+		}
+		bs, err := ioutil.ReadFile(cfg.solutionsDir(want[0]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := string(bs); !strings.Contains(code, "func Sum(a, b int) int {") || strings.Contains(code, "&") {
+			t.Errorf("%s: code of %s isn't unescaped:\n%s", exercise, want[0], code)
+		}
+
+		// test suite is stored with solutions
+		if err = checkTestSuite(cfg.solutionsDir("test-suite")); err != nil {
+			t.Errorf("%s: %v", exercise, err)
+		}
+		if bs, err = ioutil.ReadFile(cfg.solutionsDir("test-suite", "ex_test.go")); err != nil || !strings.Contains(string(bs), "i < b.N") {
+			t.Errorf("%s: test file = %q, %v", exercise, bs, err)
+		}
+	}
+}
+
+func TestTotalCmd(t *testing.T) {
+	defer func(lg *log.Logger) { mlog = lg }(mlog)
+	srv := newTestServer(t)
+
+	for exercise, want := range map[string]int{"single": len(singleUUIDs), "multi": len(multiUUIDs)} {
+		buf := &bytes.Buffer{}
+		mlog = log.New(buf, "", 0)
+		cfg := testSiteConfig(t, srv, exercise)
+		if err := totalCmd(context.Background(), cfg, testWorkers(t, 2), nil); err != nil {
+			t.Fatalf("%s: %v", exercise, err)
+		}
+		if out := buf.String(); out != fmt.Sprintf("solutions total: %d (from network)\n", want) {
+			t.Errorf("%s: output = %q, want total %d", exercise, out, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// gistsURL is a URL of GitHub API to create gists.
+const gistsURL = "https://api.github.com/gists"
+
+func shareCmd(ctx context.Context, cfg *config, _ chan<- task, args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return errInvalidUsage
+	}
+	if gistFlag && gistTokenFlag == "" {
+		return errors.New("gist upload requires GitHub token")
+	}
+	rank := 1
+	if len(args) == 3 {
+		var err error
+		if rank, err = strconv.Atoi(args[2]); err != nil || rank < 1 {
+			return errInvalidUsage
+		}
+	}
+	bn := args[1]
+
+	// find a solution with a given rank
+	sstats, err := readResults(args[0], func(n string) bool { return n == bn })
+	if err != nil {
+		return err
+	}
+	sortSolutionStatsByBench(sstats, bn, sortKeys())
+	reported := 0
+	for _, st := range sstats {
+		if st.bstats[bn] != nil {
+			reported++
+		}
+	}
+	if reported == 0 {
+		return fmt.Errorf("no results of %s in %s", bn, args[0])
+	}
+	if rank > reported {
+		return fmt.Errorf("rank %d is out of %d reported solutions", rank, reported)
+	}
+	st := sstats[rank-1]
+
+	// format a snippet with solution code
+	code, err := ioutil.ReadFile(cfg.solutionsDir(st.file))
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	formatShare(buf, cfg.exercise, bn, rank, reported, st, string(code))
+	if _, err = os.Stdout.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	// upload it on demand only
+	if !gistFlag {
+		return nil
+	}
+	urlv, err := uploadGist(ctx, cfg, st.name+".md", fmt.Sprintf("%s: %s rank %d", cfg.exercise, bn, rank), buf.String())
+	if err != nil {
+		return fmt.Errorf("gist upload failed: %v", err)
+	}
+	mlog.Printf("gist uploaded to %s", urlv)
+
+	return nil
+}
+
+// formatShare writes a Markdown snippet with stats and code of a solution ranked among reported ones.
+func formatShare(w io.Writer, exercise, bn string, rank, reported int, st *solutionStats, code string) {
+	bst := st.bstats[bn]
+	_, _, author := parseSolutionFileName(st.file)
+
+	fmt.Fprintf(w, "### %s: %s\n\n", exercise, bn)
+	if author != "" {
+		fmt.Fprintf(w, "Solution by **%s** ranked %d of %d.\n\n", author, rank, reported)
+	} else {
+		fmt.Fprintf(w, "Solution **%s** ranked %d of %d.\n\n", st.name, rank, reported)
+	}
+	fmt.Fprintf(w, "- time: %.*f ns/op\n", precisionFlag, bst.time)
+	if bst.throughput != -1 {
+		fmt.Fprintf(w, "- throughput: %.*f MB/s\n", precisionFlag, bst.throughput)
+	}
+	if bst.mem != -1 && bst.allocs != -1 {
+		fmt.Fprintf(w, "- mem: %d B/op\n", bst.mem)
+		fmt.Fprintf(w, "- allocs: %d allocs/op\n", bst.allocs)
+	}
+	fmt.Fprintf(w, "- size: %d %s\n", st.size, sizeMetricFlag)
+	if st.goVersion != "" {
+		fmt.Fprintf(w, "- go: %s\n", st.goVersion)
+	}
+
+	// a fence must be longer than any backtick run in code
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(w, "\n%sgo\n%s", fence, code)
+	if !strings.HasSuffix(code, "\n") {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%s\n", fence)
+}
+
+// uploadGist creates a secret gist with a single file and returns its URL.
+func uploadGist(ctx context.Context, cfg *config, fname, description, content string) (urlv string, err error) {
+	if offlineFlag {
+		return "", errOffline
+	}
+	bs, err := json.Marshal(map[string]interface{}{
+		"description": description,
+		"public":      false,
+		"files": map[string]interface{}{
+			fname: map[string]string{"content": content},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// create request
+	req, err := http.NewRequest("POST", gistsURL, bytes.NewReader(bs))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+gistTokenFlag)
+
+	// do request
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+	resp, err := cfg.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", &statusError{
+			code:   resp.StatusCode,
+			status: resp.Status,
+		}
+	}
+
+	gist := struct {
+		HTMLURL string `json:"html_url"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(&gist); err != nil {
+		return "", err
+	}
+	return gist.HTMLURL, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/avegner/exercism-bench/exercism"
+)
+
+// newSource returns exercism.Source of the config set by flags: API one in API mode or site pages one.
+// Solution group pages are downloaded by tasks of a queue.
+func newSource(cfg *config, tq chan<- task) exercism.Source {
+	if apiFlag {
+		return &apiSource{cfg: cfg, tq: tq, url: apiURL()}
+	}
+	return newHTMLSource(cfg, tq)
+}
+
+// groupUUIDsFunc returns UUIDs of solutions on a group page with a given number starting from 1
+// and a total number of group pages, which is known from the first page at least.
+type groupUUIDsFunc func(ctx context.Context, page uint64) (uuids []string, total uint64, urlv string, err error)
+
+// listUUIDs returns sorted UUIDs of solutions of all group pages, pages after the first one are downloaded by tasks.
+func listUUIDs(ctx context.Context, cfg *config, tq chan<- task, groupUUIDs groupUUIDsFunc) (uuids []string, err error) {
+	// get first solutions group page with total of pages
+	first, total, solutionsURL, err := groupUUIDs(ctx, 1)
+	if err != nil {
+		if isNotFound(err) {
+			err = exerciseNotFoundError(ctx, cfg, tq)
+			return
+		}
+		err = fmt.Errorf("download of %s failed: %v", solutionsURL, err)
+		return
+	}
+
+	// schedule downloads of other pages
+	wg := sync.WaitGroup{}
+	mx := sync.Mutex{}
+	seen := make(map[string]struct{})
+	addUUIDs := func(found []string) {
+		// ignore duplicates if they appear
+		mx.Lock()
+		for _, uuid := range found {
+			seen[uuid] = struct{}{}
+		}
+		mx.Unlock()
+	}
+	addUUIDs(first)
+
+	for i := uint64(1); i < total; i++ {
+		n := i
+		wg.Add(1)
+
+		tq <- func() {
+			defer wg.Done()
+			if ctx.Err() != nil {
+				return
+			}
+
+			// get solution UUIDs of a group page
+			found, _, groupURL, err := groupUUIDs(ctx, n+1)
+			if err != nil {
+				mlog.Printf("download of %s failed: %v", groupURL, err)
+				return
+			}
+			if len(found) == 0 {
+				mlog.Printf("can't find solution UUIDs in %s", groupURL)
+				return
+			}
+			addUUIDs(found)
+		}
+	}
+
+	// wait all tasks
+	wg.Wait()
+
+	for uuid := range seen {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	return uuids, nil
+}
+
+// firstUUID returns UUID of the first solution on the first group page, test suite is taken with it.
+func firstUUID(ctx context.Context, cfg *config, tq chan<- task, groupUUIDs groupUUIDsFunc) (string, error) {
+	found, _, groupURL, err := groupUUIDs(ctx, 1)
+	if err != nil {
+		if isNotFound(err) {
+			return "", exerciseNotFoundError(ctx, cfg, tq)
+		}
+		return "", fmt.Errorf("download of %s failed: %v", groupURL, err)
+	}
+	if len(found) == 0 {
+		return "", fmt.Errorf("can't find solution UUIDs in %s", groupURL)
+	}
+	return found[0], nil
+}
+
+// htmlSource scrapes solutions from site pages.
+// Group pages are scraped by exercism.SiteSource, which fetches pages with retries and cache of getPage.
+type htmlSource struct {
+	cfg  *config
+	tq   chan<- task
+	site *exercism.SiteSource
+}
+
+func newHTMLSource(cfg *config, tq chan<- task) *htmlSource {
+	return &htmlSource{cfg: cfg, tq: tq, site: &exercism.SiteSource{
+		Addr:     cfg.addr,
+		Track:    trackLang,
+		Exercise: cfg.exercise,
+		Fetch: func(ctx context.Context, urlv string) (string, error) {
+			// solution pages are small but numerous, so they have a separate timeout
+			timeout := pageTimeoutFlag
+			if solutionPathRE.MatchString(urlv) {
+				timeout = solTimeoutFlag
+			}
+			content, _, err := getPage(ctx, cfg, timeout, urlv, nil)
+			return content, err
+		},
+	}}
+}
+
+// ListUUIDs returns sorted UUIDs of solutions of all group pages.
+func (s *htmlSource) ListUUIDs(ctx context.Context) ([]string, error) {
+	return listUUIDs(ctx, s.cfg, s.tq, s.site.GroupPage)
+}
+
+// FetchSolution returns code of a solution and its author name.
+func (s *htmlSource) FetchSolution(ctx context.Context, uuid string) (code, author string, err error) {
+	solutionPage, solutionURL, err := getSolutionPage(ctx, s.cfg, uuid, nil)
+	if err == nil {
+		if code, author, err = extractSolutionCode(solutionPage); err != nil {
+			err = fmt.Errorf("code extraction failed: %v", err)
+		}
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("download of %s failed: %v", solutionURL, err)
+	}
+	return code, author, nil
+}
+
+// FetchTestSuite gets test suite of the first solution from the first solutions group page.
+func (s *htmlSource) FetchTestSuite(ctx context.Context) (suite map[string]string, err error) {
+	uuid, err := firstUUID(ctx, s.cfg, s.tq, s.site.GroupPage)
+	if err != nil {
+		return nil, err
+	}
+	solutionPage, solutionURL, err := getSolutionPage(ctx, s.cfg, uuid, nil)
+	if err == nil {
+		if suite, err = extractTestSuite(solutionPage); err != nil {
+			err = fmt.Errorf("test suite extraction failed: %v", err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("download of test suite %s failed: %v", solutionURL, err)
+	}
+	return suite, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/avegner/exercism-bench/exercism"
+)
+
+// fakeSolution is a solution of a fake source.
+type fakeSolution struct {
+	code, author string
+}
+
+// fakeSource is exercism.Source of solutions in memory.
+// Listed solutions absent in the map fail to be fetched.
+type fakeSource struct {
+	uuids     []string
+	solutions map[string]fakeSolution
+	suite     map[string]string
+}
+
+func (s *fakeSource) ListUUIDs(ctx context.Context) ([]string, error) {
+	uuids := append([]string(nil), s.uuids...)
+	sort.Strings(uuids)
+	return uuids, nil
+}
+
+func (s *fakeSource) FetchSolution(ctx context.Context, uuid string) (code, author string, err error) {
+	sol, ok := s.solutions[uuid]
+	if !ok {
+		return "", "", errors.New("no solution " + uuid)
+	}
+	return sol.code, sol.author, nil
+}
+
+func (s *fakeSource) FetchTestSuite(ctx context.Context) (map[string]string, error) {
+	if s.suite == nil {
+		return nil, exercism.ErrNoTestSuite
+	}
+	return s.suite, nil
+}
+
+// newFakeSource returns a fake source of solutions by two authors and a missing one.
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		uuids: []string{
+			"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+			"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+			"cccccccccccccccccccccccccccccccc",
+		},
+		solutions: map[string]fakeSolution{
+			"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa": {"package ex\n\nfunc Sum(a, b int) int { return a + b }\n", "alice"},
+			"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb": {"package ex\n\nfunc Sum(a, b int) int { return b + a }\n", "bob"},
+		},
+		suite: map[string]string{
+			"ex_test.go": "package ex\n\nimport \"testing\"\n\nfunc BenchmarkSum(b *testing.B) {}\n",
+		},
+	}
+}
+
+// fakeSourceConfig returns a config with a given source and solutions dir in a temp dir.
+func fakeSourceConfig(t *testing.T, src exercism.Source) *config {
+	useDefaultNameTemplate(t)
+	cfg := testConfig(t)
+	cfg.source = src
+	return cfg
+}
+
+func TestDownloadCmdFakeSource(t *testing.T) {
+	defer func(lg *log.Logger) { mlog = lg }(mlog)
+	buf := &bytes.Buffer{}
+	mlog = log.New(buf, "", 0)
+	cfg := fakeSourceConfig(t, newFakeSource())
+
+	if err := downloadCmd(context.Background(), cfg, testWorkers(t, 2), nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []string{"solutions total:     3", "downloaded:          2", "failed:              1"} {
+		if !strings.Contains(buf.String(), l+"\n") {
+			t.Errorf("no %q in output:\n%s", l, buf)
+		}
+	}
+	fnames, err := listSolutionFiles(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa-alice.go", "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb-bob.go"}
+	if strings.Join(fnames, " ") != strings.Join(want, " ") {
+		t.Errorf("stored solutions = %v, want %v", fnames, want)
+	}
+	if bs, err := ioutil.ReadFile(cfg.solutionsDir("test-suite", "ex_test.go")); err != nil || !strings.Contains(string(bs), "BenchmarkSum") {
+		t.Errorf("test file = %q, %v", bs, err)
+	}
+}
+
+func TestSuiteCmdFakeSource(t *testing.T) {
+	defer func(lg *log.Logger) { mlog = lg }(mlog)
+	mlog = log.New(ioutil.Discard, "", 0)
+	src := newFakeSource()
+	cfg := fakeSourceConfig(t, src)
+
+	if err := suiteCmd(context.Background(), cfg, testWorkers(t, 1), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkTestSuite(cfg.solutionsDir("test-suite")); err != nil {
+		t.Error(err)
+	}
+
+	src.suite = nil
+	if err := suiteCmd(context.Background(), cfg, testWorkers(t, 1), nil); !errors.Is(err, exercism.ErrNoTestSuite) {
+		t.Errorf("error = %v, want %v", err, exercism.ErrNoTestSuite)
+	}
+}
+
+func TestDownloadCmdRefreshSuite(t *testing.T) {
+	defer func(lg *log.Logger) { mlog = lg }(mlog)
+	mlog = log.New(ioutil.Discard, "", 0)
+	defer func(v bool) { refreshSuiteFlag = v }(refreshSuiteFlag)
+	refreshSuiteFlag = true
+	cfg := fakeSourceConfig(t, newFakeSource())
+	writeTestFiles(t, cfg.solutionsDir(), "test-suite/ex_test.go")
+
+	if err := downloadCmd(context.Background(), cfg, testWorkers(t, 2), nil); err != nil {
+		t.Fatal(err)
+	}
+	if bs, err := ioutil.ReadFile(cfg.solutionsDir("test-suite", "ex_test.go")); err != nil || !strings.Contains(string(bs), "BenchmarkSum") {
+		t.Errorf("test file = %q, %v", bs, err)
+	}
+	// only test suite is refreshed
+	if fnames, err := listSolutionFiles(cfg); err != nil || len(fnames) != 0 {
+		t.Errorf("stored solutions = %v, %v, want none", fnames, err)
+	}
+}
+
+func TestTotalCmdFakeSource(t *testing.T) {
+	defer func(lg *log.Logger) { mlog = lg }(mlog)
+	buf := &bytes.Buffer{}
+	mlog = log.New(buf, "", 0)
+	cfg := fakeSourceConfig(t, newFakeSource())
+
+	if err := totalCmd(context.Background(), cfg, testWorkers(t, 1), nil); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); out != "solutions total: 3 (from network)\n" {
+		t.Errorf("output = %q, want total 3", out)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// mannWhitneyU returns two-sided p-value of Mann-Whitney U test for two samples.
+// Normal approximation with tie correction is used, so it's rough for samples of less than ~8 values.
+func mannWhitneyU(xs, ys []float64) (p float64) {
+	n1, n2 := float64(len(xs)), float64(len(ys))
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	// rank all values together, ties get an average rank
+	type value struct {
+		v     float64
+		first bool
+	}
+	vs := make([]value, 0, len(xs)+len(ys))
+	for _, x := range xs {
+		vs = append(vs, value{x, true})
+	}
+	for _, y := range ys {
+		vs = append(vs, value{y, false})
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i].v < vs[j].v })
+
+	r1, ties := 0.0, 0.0
+	for i := 0; i < len(vs); {
+		j := i
+		for j < len(vs) && vs[j].v == vs[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if vs[k].first {
+				r1 += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	// normal approximation
+	u := r1 - n1*(n1+1)/2
+	n := n1 + n2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := (u - n1*n2/2) / sigma
+	return math.Erfc(math.Abs(z) / math.Sqrt2)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// suiteHashFileName is a name of a file in solutions dir with a hash of test suite solutions were downloaded against.
+const suiteHashFileName = "test-suite.sha256"
+
+// hashTestSuite returns a combined hash of all files in test suite dir and its nested dirs.
+// Both relative paths and contents of files are hashed, so renames are caught too.
+func hashTestSuite(dir string) (string, error) {
+	paths := []string{}
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if regular(fi) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, p := range paths {
+		bs, err := ioutil.ReadFile(p)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(rel), len(bs))
+		h.Write(bs)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// saveSuiteHash records a hash of the downloaded test suite.
+func saveSuiteHash(cfg *config) error {
+	hash, err := hashTestSuite(cfg.solutionsDir("test-suite"))
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(cfg.solutionsDir(suiteHashFileName), []byte(hash+"\n"), 0600)
+}
+
+// checkSuiteHash compares a hash of test suite in a given dir with the recorded one.
+// Solutions downloaded before hashes were recorded have nothing to compare with.
+func checkSuiteHash(cfg *config, tsDir string) error {
+	bs, err := ioutil.ReadFile(cfg.solutionsDir(suiteHashFileName))
+	if os.IsNotExist(err) {
+		vlogf("no test suite hash recorded, its check skipped")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	hash, err := hashTestSuite(tsDir)
+	if err != nil {
+		return err
+	}
+	if recorded := strings.TrimSpace(string(bs)); hash != recorded {
+		return fmt.Errorf("test suite %s (%.12s) differs from one solutions were downloaded against (%.12s)",
+			tsDir, hash, recorded)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestBenchCmdCustomSuiteHash(t *testing.T) {
+	defer func(lg *log.Logger, tmpl *template.Template) { mlog, nameTemplate = lg, tmpl }(mlog, nameTemplate)
+	defer func(strict bool, suite string, bt time.Duration) {
+		strictSuiteFlag, suiteDirFlag, benchtimeFlag = strict, suite, bt
+	}(strictSuiteFlag, suiteDirFlag, benchtimeFlag)
+	mlog = log.New(ioutil.Discard, "", 0)
+	tmpl, err := parseNameTemplate(nameTemplateFlag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nameTemplate = tmpl
+	strictSuiteFlag, benchtimeFlag = true, time.Millisecond
+
+	// downloaded suite doesn't match its recorded hash
+	cfg := testConfig(t)
+	custom := t.TempDir()
+	suite := map[string]string{
+		"go.mod":     "module ex\n\ngo 1.16\n",
+		"ex_test.go": "package ex\n\nimport \"testing\"\n\nfunc BenchmarkSum(b *testing.B) {\n\tfor i := 0; i < b.N; i++ {\n\t\tSum(i, 1)\n\t}\n}\n",
+	}
+	for _, dir := range []string{cfg.solutionsDir("test-suite"), custom} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		for fn, fc := range suite {
+			if err := ioutil.WriteFile(filepath.Join(dir, fn), []byte(fc), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	files := map[string]string{
+		suiteHashFileName: "0123456789abcdef\n",
+		"a.go":            "package ex\n\nfunc Sum(a, b int) int {\n\treturn a + b\n}\n",
+	}
+	for fn, fc := range files {
+		if err := ioutil.WriteFile(cfg.solutionsDir(fn), []byte(fc), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = benchCmd(context.Background(), cfg, testWorkers(t, 1), nil)
+	if err == nil || !strings.Contains(err.Error(), "differs") {
+		t.Fatalf("error of downloaded suite = %v, want hash mismatch", err)
+	}
+
+	// a custom suite isn't checked
+	suiteDirFlag = custom
+	if err = benchCmd(context.Background(), cfg, testWorkers(t, 1), nil); err != nil {
+		t.Errorf("error of custom suite = %v", err)
+	}
+}
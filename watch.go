@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/avegner/exercism-bench/exercism"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is a time a changed file must stay intact to be benched, so rapid writes give one bench.
+const watchDebounce = 300 * time.Millisecond
+
+// fileStamp identifies a version of a file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func watchCmd(ctx context.Context, cfg *config, _ chan<- task, args []string) error {
+	if len(args) != 0 {
+		return errInvalidUsage
+	}
+
+	// check test suite
+	tsDir := testSuiteDir(cfg)
+	if err := checkTestSuite(tsDir); err != nil {
+		return err
+	}
+	bnames, err := getBenchNames("", tsDir)
+	if err != nil {
+		return err
+	}
+	toolchains, err := getToolchains(ctx)
+	if err != nil {
+		return err
+	}
+
+	// watch solutions dir and dirs of nested layout
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err = w.Add(cfg.solutionsDir()); err != nil {
+		return err
+	}
+	fis, err := ioutil.ReadDir(cfg.solutionsDir())
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		if fi.IsDir() && fi.Name() != "test-suite" {
+			if err = w.Add(cfg.solutionsDir(fi.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	// existing files are benched only after changes
+	known, err := stampSolutionFiles(cfg)
+	if err != nil {
+		return err
+	}
+	mlog.Printf("watching %s for changes of %d solutions, interrupt to stop", cfg.solutionsDir(), len(known))
+	mlog.Println()
+
+	// each change of a file restarts its timer, names of intact files are sent to due
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	timers := make(map[string]*time.Timer)
+	due := make(chan string)
+	for {
+		select {
+		case <-ctx.Done():
+			// interrupt is the only way to stop watching
+			for _, t := range timers {
+				t.Stop()
+			}
+			return nil
+		case err := <-w.Errors:
+			return err
+		case ev := <-w.Events:
+			fn := watchedSolutionFile(cfg, w, ev)
+			if fn == "" {
+				continue
+			}
+			if t := timers[fn]; t != nil {
+				t.Reset(watchDebounce)
+				continue
+			}
+			timers[fn] = time.AfterFunc(watchDebounce, func() {
+				select {
+				case due <- fn:
+				case <-ctx.Done():
+				}
+			})
+		case fn := <-due:
+			delete(timers, fn)
+			st, err := stampSolutionFile(cfg, fn)
+			if err != nil {
+				// removed or being replaced by an editor
+				delete(known, fn)
+				continue
+			}
+			if known[fn] == st {
+				continue
+			}
+			known[fn] = st
+			benchWatched(ctx, cfg, tsDir, fn, toolchains, bnames)
+		}
+	}
+}
+
+// watchedSolutionFile returns a name of a solution file relative to solutions dir changed by an event
+// or an empty string for other files. A created dir of nested layout is added to a watcher.
+func watchedSolutionFile(cfg *config, w *fsnotify.Watcher, ev fsnotify.Event) string {
+	fn, err := filepath.Rel(cfg.solutionsDir(), ev.Name)
+	if err != nil || ignoredWatchFile(fn) {
+		return ""
+	}
+	dir, name := filepath.Split(fn)
+	switch {
+	case dir == "" && ev.Has(fsnotify.Create):
+		if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+			if name == "test-suite" {
+				return ""
+			}
+			if err = w.Add(ev.Name); err != nil {
+				mlog.Printf("watch of %s failed: %v", ev.Name, err)
+				return ""
+			}
+			// its solution file can be created before the dir is watched
+			return filepath.Join(name, name+".go")
+		}
+	case dir != "" && filepath.Clean(dir) != strings.TrimSuffix(name, ".go"):
+		return ""
+	}
+	if filepath.Ext(fn) != ".go" {
+		return ""
+	}
+	return fn
+}
+
+// ignoredWatchFile checks a file is a hidden or lock file of editors.
+func ignoredWatchFile(fn string) bool {
+	n := filepath.Base(fn)
+	return strings.HasPrefix(n, ".") || strings.HasPrefix(n, "#")
+}
+
+// stampSolutionFiles returns stamps of solution files by their names.
+// Hidden and lock files of editors are ignored.
+func stampSolutionFiles(cfg *config) (stamps map[string]fileStamp, err error) {
+	fnames, err := listSolutionFiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+	stamps = make(map[string]fileStamp, len(fnames))
+	for _, fn := range fnames {
+		if ignoredWatchFile(fn) {
+			continue
+		}
+		st, err := stampSolutionFile(cfg, fn)
+		if err != nil {
+			// the file can be replaced by an editor right now
+			continue
+		}
+		stamps[fn] = st
+	}
+	return stamps, nil
+}
+
+// stampSolutionFile returns a stamp of a solution file.
+func stampSolutionFile(cfg *config, fn string) (fileStamp, error) {
+	fi, err := os.Stat(cfg.solutionsDir(fn))
+	if err != nil {
+		return fileStamp{}, err
+	}
+	if !regular(fi) {
+		return fileStamp{}, errors.New("not a regular file")
+	}
+	return fileStamp{modTime: fi.ModTime(), size: fi.Size()}, nil
+}
+
+// benchWatched benches a changed solution file with each toolchain and prints its stats.
+func benchWatched(ctx context.Context, cfg *config, tsDir, fname string, toolchains []*exercism.Toolchain, bnames []string) {
+	size, err := getCodeSize(cfg.solutionsDir(fname))
+	if err != nil {
+		mlog.Printf("bench of %s failed: %v", fname, err)
+		mlog.Println()
+		return
+	}
+	tmp, err := makeBuildDir(cfg, tsDir, fname)
+	if err != nil {
+		mlog.Printf("build dir of %s failed: %v", fname, err)
+		mlog.Println()
+		return
+	}
+	defer os.RemoveAll(tmp)
+
+	labels := toolchainLabels(toolchains)
+	for i, tc := range toolchains {
+		mlog.Printf("%s @%s (%s):", fname, labels[i], time.Now().Format("15:04:05"))
+		bstats, out, err := benchSolution(ctx, tc, labels[i], tmp, fname)
+		if _, partial := err.(*exercism.PartialError); err != nil && !partial {
+			mlog.Printf("bench failed: %v\n%s", err, out)
+			mlog.Println()
+			continue
+		}
+		for _, bn := range bnames {
+			if bst := bstats[bn]; bst != nil {
+				mlog.Printf("%-40s: %s %15d %s", bn, bst, size, sizeMetricFlag)
+			} else {
+				mlog.Printf("%-40s: no result", bn)
+			}
+		}
+		mlog.Println()
+	}
+}
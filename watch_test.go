@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatchedSolutionFile(t *testing.T) {
+	cfg := testConfig(t)
+	writeTestFiles(t, cfg.solutionsDir(), "nested/nested.go", "test-suite/ex_test.go")
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	cases := []struct {
+		name string
+		op   fsnotify.Op
+		want string
+	}{
+		{"a.go", fsnotify.Write, "a.go"},
+		{"a.go", fsnotify.Remove, "a.go"},
+		{"notes.txt", fsnotify.Write, ""},
+		{".a.go.swp", fsnotify.Create, ""},
+		{".#a.go", fsnotify.Create, ""},
+		{"nested", fsnotify.Create, filepath.Join("nested", "nested.go")},
+		{filepath.Join("nested", "nested.go"), fsnotify.Write, filepath.Join("nested", "nested.go")},
+		{filepath.Join("nested", "other.go"), fsnotify.Write, ""},
+		{"test-suite", fsnotify.Create, ""},
+	}
+	for _, c := range cases {
+		ev := fsnotify.Event{Name: cfg.solutionsDir(c.name), Op: c.op}
+		if got := watchedSolutionFile(cfg, w, ev); got != c.want {
+			t.Errorf("%s of %s: got %q, want %q", c.op, c.name, got, c.want)
+		}
+	}
+	if ws := w.WatchList(); len(ws) != 1 || ws[0] != cfg.solutionsDir("nested") {
+		t.Errorf("watched dirs = %v, want only nested one", ws)
+	}
+}
+
+func TestWatchCmdCancel(t *testing.T) {
+	defer func(l *log.Logger) { mlog = l }(mlog)
+	mlog = log.New(ioutil.Discard, "", 0)
+	cfg := testConfig(t)
+	writeTestFiles(t, cfg.solutionsDir(), "test-suite/ex_test.go", "a.go")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(500*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() { done <- watchCmd(ctx, cfg, nil, nil) }()
+	select {
+	case err := <-done:
+		// interrupt is the way to stop watching, so it isn't an error
+		if err != nil {
+			t.Errorf("error = %v, want nil", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("watch hasn't stopped")
+	}
+}
+
+func TestRunWatchInterrupt(t *testing.T) {
+	defer func(l *log.Logger) { mlog = l }(mlog)
+	mlog = log.New(ioutil.Discard, "", 0)
+	defer func(v string) { downloadDirFlag = v }(downloadDirFlag)
+	cfg := testConfig(t)
+	downloadDirFlag = cfg.dir
+	writeTestFiles(t, cfg.solutionsDir(), "test-suite/ex_test.go", "a.go")
+
+	// simulate an interrupt once watching has started
+	defer func(f func(context.Context, ...os.Signal) (context.Context, context.CancelFunc)) { notifyContext = f }(notifyContext)
+	notifyContext = func(parent context.Context, _ ...os.Signal) (context.Context, context.CancelFunc) {
+		return context.WithTimeout(parent, 500*time.Millisecond)
+	}
+	done := make(chan error, 1)
+	go func() { done <- run([]string{"ex", "watch"}) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("run error = %v, want nil", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("watch hasn't stopped")
+	}
+}